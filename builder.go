@@ -4,6 +4,7 @@ package sayori
 type Builder struct {
 	handler     interface{}
 	filter      Filter
+	fmtErr      func(Filter) string
 	middlewares []Middleware
 }
 
@@ -32,6 +33,13 @@ func (b *Builder) Filter(f Filter) *Builder {
 	return b
 }
 
+// FmtFilterErr sets the function converting failing Filter(s) into the error string of the FilterError
+// passed to Resolve, such as the Format method of custom or localized FilterMessages.
+func (b *Builder) FmtFilterErr(fmtErr func(Filter) string) *Builder {
+	b.fmtErr = fmtErr
+	return b
+}
+
 // Use a custom middleware. Middlewares are executed in the order they are chained via Builder.
 // Middlewares run AFTER all Filters are run.
 func (b *Builder) Use(m Middleware) *Builder {
@@ -1,14 +1,12 @@
 package sayori
 
 import (
-	"fmt"
-
 	"github.com/bwmarrin/discordgo"
 )
 
 // defaultFmtFilterErr is the default format function to convert failing Filter(s) into an error string
 func defaultFmtFilterErr(f Filter) string {
-	return fmt.Sprintf("filter fail code '%d'", f)
+	return DefaultFilterMessages.Format(f)
 }
 
 // Context contains data relating to the command invocation context
@@ -21,7 +19,7 @@ type Context struct {
 	Args         Args
 	Toks         Toks
 	Err          error
-	FmtFilterErr func(Filter) string // format a Filter into an error string
+	FmtFilterErr func(Filter) string // format a Filter into an error string, set with Builder.FmtFilterErr
 }
 
 // filterToErr converts an error string to a RuleError
@@ -32,7 +32,7 @@ func (*OnMsg) Resolve(ctx sayori.Context) {
 	switch e := ctx.Err.(type) {
 	case *sayori.FilterError:
 		if e.Filter().Contains(sayori.MessagesSelf) {
-			log.Printf("Filter failed for OnMsg; id:'%d'", e.Filter())
+			log.Printf("Filter failed for OnMsg: %s", e.Filter())
 		} else {
 			_, _ = ctx.Session.ChannelMessageSend(
 				ctx.Message.ChannelID, e.Error())
@@ -1,5 +1,11 @@
 package sayori
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Filter represents a condition that prevents a `Command` or `Event` from firing.
 // Only use the given const filters which range from 2^0 to 2^4.
 type Filter int
@@ -15,6 +21,11 @@ func (e *FilterError) Filter() Filter {
 	return e.f
 }
 
+// Failed returns each violated flag, in order of bit offset.
+func (e *FilterError) Failed() []Filter {
+	return e.f.Flags()
+}
+
 func (e *FilterError) Error() string {
 	return e.reason
 }
@@ -28,6 +39,96 @@ const (
 	MessagesGuild
 )
 
+// filterNames names each Filter flag, in order of bit offset.
+//
+// The flag names, FilterMessages and their methods mirror the v2 filter package (v2/filter/messages.go);
+// the modules are versioned separately, so changes to one should be carried over to the other.
+var filterNames = []struct {
+	f    Filter
+	name string
+}{
+	{MessagesSelf, "MessagesSelf"},
+	{MessagesBot, "MessagesBot"},
+	{MessagesEmpty, "MessagesEmpty"},
+	{MessagesPrivate, "MessagesPrivate"},
+	{MessagesGuild, "MessagesGuild"},
+}
+
+// FilterMessages maps single Filter flags to human-readable messages explaining why a message was filtered.
+// A FilterMessages per language can be used to localize filter errors.
+//
+// The zero Filter may be mapped to the message used when the message could not be inspected at all.
+type FilterMessages map[Filter]string
+
+// DefaultFilterMessages are the English FilterMessages used to format filter errors by default.
+var DefaultFilterMessages = FilterMessages{
+	0:               "message could not be inspected",
+	MessagesSelf:    "message was sent by the bot itself",
+	MessagesBot:     "message was sent by a bot",
+	MessagesEmpty:   "message has no content",
+	MessagesPrivate: "message was sent in a direct message",
+	MessagesGuild:   "message was sent in a server",
+}
+
+// Format joins the message of each flag of f with "; ".
+// Flags with no message fall back to DefaultFilterMessages, and then to the name of the flag.
+//
+// The method value can be passed to Builder.FmtFilterErr.
+func (m FilterMessages) Format(f Filter) string {
+	flags := f.Flags()
+	if len(flags) == 0 {
+		flags = []Filter{0}
+	}
+
+	msgs := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		msg, ok := m[flag]
+		if !ok {
+			if msg, ok = DefaultFilterMessages[flag]; !ok {
+				msg = flag.String()
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Flags decomposes f into single flags, in order of bit offset.
+func (f Filter) Flags() []Filter {
+	var flags []Filter
+	for i := 0; i < strconv.IntSize; i++ {
+		if bit := Filter(1) << i; f&bit != 0 {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}
+
+// String returns the names of the flags of f joined by "|", such as "MessagesBot|MessagesPrivate".
+// Unknown flags are written in hexadecimal, and the zero Filter is "0".
+func (f Filter) String() string {
+	flags := f.Flags()
+	if len(flags) == 0 {
+		return "0"
+	}
+
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, filterName(flag))
+	}
+	return strings.Join(names, "|")
+}
+
+// filterName returns the name of a single flag.
+func filterName(flag Filter) string {
+	for _, n := range filterNames {
+		if n.f == flag {
+			return n.name
+		}
+	}
+	return fmt.Sprintf("0x%x", int(flag))
+}
+
 // NewFilter generates a Filter bitset given filters and performing a bitwise `or` on all of them
 func NewFilter(filters ...Filter) Filter {
 	var filter Filter
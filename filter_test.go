@@ -1,6 +1,7 @@
 package sayori
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/bwmarrin/discordgo"
@@ -153,3 +154,40 @@ func TestFilter(t *testing.T) {
 
 	})
 }
+
+func TestFilter_String(t *testing.T) {
+	tests := []struct {
+		f        Filter
+		expected string
+	}{
+		{Filter(0), "0"},
+		{MessagesBot, "MessagesBot"},
+		{NewFilter(MessagesPrivate, MessagesBot), "MessagesBot|MessagesPrivate"},
+		{NewFilter(MessagesSelf, Filter(1<<10)), "MessagesSelf|0x400"},
+	}
+
+	for _, tt := range tests {
+		if s := tt.f.String(); s != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, s)
+		}
+	}
+}
+
+func TestFilterMessages_Format(t *testing.T) {
+	ctx := NewContext()
+	err := ctx.filterToErr(NewFilter(MessagesGuild, MessagesSelf))
+	if err.Error() != "message was sent by the bot itself; message was sent in a server" {
+		t.Errorf("unexpected default message: %s", err)
+	}
+
+	ctx.FmtFilterErr = FilterMessages{MessagesSelf: "ignoring myself"}.Format
+	err = ctx.filterToErr(NewFilter(MessagesGuild, MessagesSelf))
+	if err.Error() != "ignoring myself; message was sent in a server" {
+		t.Errorf("unexpected custom message: %s", err)
+	}
+
+	failed := err.(*FilterError).Failed()
+	if !reflect.DeepEqual(failed, []Filter{MessagesSelf, MessagesGuild}) {
+		t.Errorf("expected failed flags to be listed, got %v", failed)
+	}
+}
@@ -135,9 +135,9 @@ func (r *Router) Has(b *Builder) {
 
 	switch v := b.handler.(type) {
 	case Command:
-		newHandler = r.makeCommand(v, b.filter, b.middlewares)
+		newHandler = r.makeCommand(v, b.filter, b.fmtErr, b.middlewares)
 	case Event:
-		newHandler = r.makeEvent(v, b.filter, b.middlewares)
+		newHandler = r.makeEvent(v, b.filter, b.fmtErr, b.middlewares)
 	default:
 		newHandler = b.handler
 	}
@@ -155,9 +155,9 @@ func (r *Router) HasOnce(b *Builder) {
 
 	switch v := b.handler.(type) {
 	case Command:
-		newHandler = r.makeCommand(v, b.filter, b.middlewares)
+		newHandler = r.makeCommand(v, b.filter, b.fmtErr, b.middlewares)
 	case Event:
-		newHandler = r.makeEvent(v, b.filter, b.middlewares)
+		newHandler = r.makeEvent(v, b.filter, b.fmtErr, b.middlewares)
 	default:
 		newHandler = b.handler
 	}
@@ -200,9 +200,12 @@ func handleMiddlewares(ctx Context, m []Middleware) error {
 }
 
 // makeEvent registers a MessageCreate event handler that does not require an alias or prefix
-func (r *Router) makeEvent(e Event, f Filter, mws []Middleware) func(*discordgo.Session, *discordgo.MessageCreate) {
+func (r *Router) makeEvent(e Event, f Filter, fmtErr func(Filter) string, mws []Middleware) func(*discordgo.Session, *discordgo.MessageCreate) {
 	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
 		ctx := NewContext()
+		if fmtErr != nil {
+			ctx.FmtFilterErr = fmtErr
+		}
 		ctx.Session = s
 		ctx.Message = m.Message
 		ctx.Toks = NewToks(m.Message.Content)
@@ -232,7 +235,7 @@ func (r *Router) makeEvent(e Event, f Filter, mws []Middleware) func(*discordgo.
 }
 
 // makeCommand registers a command
-func (r *Router) makeCommand(c Command, f Filter, mws []Middleware) func(*discordgo.Session, *discordgo.MessageCreate) {
+func (r *Router) makeCommand(c Command, f Filter, fmtErr func(Filter) string, mws []Middleware) func(*discordgo.Session, *discordgo.MessageCreate) {
 	var (
 		prefix, alias, cmd string
 		ok                 bool
@@ -252,6 +255,9 @@ func (r *Router) makeCommand(c Command, f Filter, mws []Middleware) func(*discor
 		}
 
 		ctx := NewContext()
+		if fmtErr != nil {
+			ctx.FmtFilterErr = fmtErr
+		}
 		ctx.Session = s
 		ctx.Alias = alias
 		ctx.Prefix = prefix
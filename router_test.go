@@ -121,7 +121,7 @@ func testEvent(
 		t.Fatal("ResolveCallback cannot be nil")
 	}
 
-	r.makeEvent(event, filter, middlewares)(mockSession, incomingMockMessage)
+	r.makeEvent(event, filter, nil, middlewares)(mockSession, incomingMockMessage)
 }
 
 func testCommand(
@@ -161,7 +161,7 @@ func testCommand(
 		t.Fatal("MatchCallback cannot be nil")
 	}
 
-	r.makeCommand(cmd, filter, middlewares)(mockSession, incomingMockMessage)
+	r.makeCommand(cmd, filter, nil, middlewares)(mockSession, incomingMockMessage)
 
 }
 
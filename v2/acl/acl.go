@@ -0,0 +1,223 @@
+// Package acl provides a middleware that restricts routes to allowed users, roles, channels and guilds.
+package acl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Kind is the kind of ID a Rule matches.
+type Kind string
+
+// Kinds of IDs a Rule can match.
+const (
+	KindUser    Kind = "user"    // matches the ID of the author of a message
+	KindRole    Kind = "role"    // matches any role of the author of a message
+	KindChannel Kind = "channel" // matches the channel of a message
+	KindGuild   Kind = "guild"   // matches the guild of a message
+)
+
+// Effect determines whether a matching Rule allows or denies a message.
+type Effect int
+
+// Effects of a Rule.
+const (
+	Allow Effect = iota
+	Deny
+)
+
+type (
+	// Rule allows or denies messages matching an ID of a Kind.
+	Rule struct {
+		Kind   Kind
+		ID     string
+		Effect Effect
+	}
+
+	// Subject describes the origin of a message that rules are evaluated against.
+	Subject struct {
+		UserID    string
+		ChannelID string
+		GuildID   string // empty in direct messages
+		Roles     []string
+	}
+
+	// ACL is a Middlewarer that only lets messages permitted by its rules through.
+	//
+	// Rules are loaded from a Store on every message, so they can be changed at runtime.
+	// Global rules, stored with an empty guild ID, apply in every guild and in direct messages.
+	// Rules stored with the ID of a guild only apply in that guild.
+	//
+	// A message is denied if it matches any Deny rule. Otherwise, for each Kind with at least one Allow rule,
+	// the message must match one of them. For example, allowing a channel and a role only permits
+	// members with the role in that channel. A message is allowed if there are no rules.
+	ACL struct {
+		name  string
+		store Store
+	}
+
+	// Error is returned by ACL when a message is not permitted.
+	Error struct {
+		ACL  string // name of the ACL
+		Kind Kind   // kind of the rule that denied the message
+		// Rule is the Deny rule the message matched.
+		// If nil, the message did not match any Allow rule of Kind.
+		Rule *Rule
+	}
+)
+
+func (e *Error) Error() string {
+	if e.Rule != nil {
+		return fmt.Sprintf("access denied: %s %s is denied", e.Rule.Kind, e.Rule.ID)
+	}
+	return fmt.Sprintf("access denied: %s is not allowed", e.Kind)
+}
+
+// New returns an ACL evaluating the rules stored under name in store.
+func New(name string, store Store) *ACL {
+	return &ACL{name: name, store: store}
+}
+
+// Do returns an *Error if the message is not permitted.
+//
+// If the Context holds a reaction instead of a message, such as in the reaction middlewares of a Router,
+// the user adding or removing the reaction is checked.
+func (a *ACL) Do(ctx context.Context) error {
+	if msg := utils.GetMsg(ctx); msg != nil {
+		return a.Check(utils.GetSes(ctx), msg)
+	}
+	if reaction := utils.GetReaction(ctx); reaction != nil {
+		return a.CheckReaction(utils.GetSes(ctx), reaction)
+	}
+	return nil
+}
+
+// Check returns an *Error if the message is not permitted.
+//
+// If a rule matches roles, the roles of the author are read from Session.State,
+// or requested from Discord if the member is not cached.
+func (a *ACL) Check(s *discordgo.Session, m *discordgo.Message) error {
+	subject := Subject{ChannelID: m.ChannelID, GuildID: m.GuildID}
+	if m.Author != nil {
+		subject.UserID = m.Author.ID
+	}
+	return a.check(s, subject, m.Member)
+}
+
+// CheckReaction returns an *Error if the user of the reaction is not permitted.
+//
+// If a rule matches roles, the roles of the user are read from Session.State,
+// or requested from Discord if the member is not cached.
+func (a *ACL) CheckReaction(s *discordgo.Session, r *discordgo.MessageReaction) error {
+	return a.check(s, Subject{UserID: r.UserID, ChannelID: r.ChannelID, GuildID: r.GuildID}, nil)
+}
+
+// check evaluates the rules of the guild of subject, loading the roles of the user if a rule matches roles.
+// member is the member sent along with the event, and may be nil.
+func (a *ACL) check(s *discordgo.Session, subject Subject, member *discordgo.Member) error {
+	rules, err := a.store.Rules(a.name, "")
+	if err != nil {
+		return err
+	}
+	if subject.GuildID != "" {
+		guildRules, err := a.store.Rules(a.name, subject.GuildID)
+		if err != nil {
+			return err
+		}
+		rules = append(rules[:len(rules):len(rules)], guildRules...)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	if subject.GuildID != "" && subject.UserID != "" && hasKind(rules, KindRole) {
+		if subject.Roles, err = memberRoles(s, subject.GuildID, subject.UserID, member); err != nil {
+			return err
+		}
+	}
+
+	if err := Evaluate(rules, subject); err != nil {
+		err.ACL = a.name
+		return err
+	}
+	return nil
+}
+
+// Evaluate returns an *Error if subject is not permitted by rules.
+func Evaluate(rules []Rule, subject Subject) *Error {
+	var (
+		allowed = map[Kind]bool{}
+		kinds   []Kind // kinds with Allow rules, in order of first appearance
+	)
+
+	for i, r := range rules {
+		matched := matches(r, subject)
+		if r.Effect == Deny {
+			if matched {
+				return &Error{Kind: r.Kind, Rule: &rules[i]}
+			}
+			continue
+		}
+
+		if _, ok := allowed[r.Kind]; !ok {
+			kinds = append(kinds, r.Kind)
+		}
+		allowed[r.Kind] = allowed[r.Kind] || matched
+	}
+
+	for _, k := range kinds {
+		if !allowed[k] {
+			return &Error{Kind: k}
+		}
+	}
+	return nil
+}
+
+// matches returns true if the ID of the rule matches the subject.
+func matches(r Rule, subject Subject) bool {
+	switch r.Kind {
+	case KindUser:
+		return r.ID == subject.UserID
+	case KindChannel:
+		return r.ID == subject.ChannelID
+	case KindGuild:
+		return r.ID == subject.GuildID
+	case KindRole:
+		for _, role := range subject.Roles {
+			if r.ID == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasKind returns true if any rule matches IDs of kind k.
+func hasKind(rules []Rule, k Kind) bool {
+	for _, r := range rules {
+		if r.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// memberRoles returns the roles of a guild member, preferring Session.State and then member, which may be nil.
+func memberRoles(s *discordgo.Session, guildID, userID string, member *discordgo.Member) ([]string, error) {
+	if cached, err := s.State.Member(guildID, userID); err == nil {
+		return cached.Roles, nil
+	}
+	if member != nil {
+		return member.Roles, nil
+	}
+
+	member, err := s.GuildMember(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return member.Roles, nil
+}
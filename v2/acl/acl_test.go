@@ -0,0 +1,125 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func testNewSes(t *testing.T) *discordgo.Session {
+	state := discordgo.NewState()
+	err := state.GuildAdd(&discordgo.Guild{
+		ID: "guild_id_1",
+		Members: []*discordgo.Member{
+			{GuildID: "guild_id_1", User: &discordgo.User{ID: "mod_id"}, Roles: []string{"role_mod"}},
+			{GuildID: "guild_id_1", User: &discordgo.User{ID: "member_id"}, Roles: []string{"role_member"}},
+			{GuildID: "guild_id_1", User: &discordgo.User{ID: "muted_id"}, Roles: []string{"role_mod", "role_muted"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &discordgo.Session{State: state}
+}
+
+func testNewCtx(ses *discordgo.Session, authorID, channelID, guildID string) context.Context {
+	ctx := utils.WithSes(context.Background(), ses)
+	return utils.WithMsg(ctx, &discordgo.Message{
+		Author:    &discordgo.User{ID: authorID},
+		ChannelID: channelID,
+		GuildID:   guildID,
+	})
+}
+
+func TestACL_Do(t *testing.T) {
+	var (
+		ses   = testNewSes(t)
+		store = NewMemoryStore()
+		a     = New("commands", store)
+	)
+
+	// no rules allow everything
+	if err := a.Do(testNewCtx(ses, "member_id", "general", "guild_id_1")); err != nil {
+		t.Fatalf("expected no rules to allow, got %v", err)
+	}
+
+	_ = store.Add("commands", "", Rule{Kind: KindUser, ID: "banned_id", Effect: Deny})
+	_ = store.Add("commands", "guild_id_1", Rule{Kind: KindChannel, ID: "bot-commands", Effect: Allow})
+	_ = store.Add("commands", "guild_id_1", Rule{Kind: KindRole, ID: "role_mod", Effect: Allow})
+	_ = store.Add("commands", "guild_id_1", Rule{Kind: KindRole, ID: "role_muted", Effect: Deny})
+	_ = store.Add("other", "guild_id_1", Rule{Kind: KindUser, ID: "mod_id", Effect: Deny})
+
+	tests := []struct {
+		author, channel, guild string
+		kind                   Kind // expected kind of the error, empty if allowed
+		denied                 bool // expected a Deny rule to match
+	}{
+		{author: "mod_id", channel: "bot-commands", guild: "guild_id_1"},
+		{author: "mod_id", channel: "general", guild: "guild_id_1", kind: KindChannel},
+		{author: "member_id", channel: "bot-commands", guild: "guild_id_1", kind: KindRole},
+		{author: "muted_id", channel: "bot-commands", guild: "guild_id_1", kind: KindRole, denied: true},
+		{author: "banned_id", channel: "dm", kind: KindUser, denied: true},
+		{author: "member_id", channel: "dm"},
+		{author: "member_id", channel: "general", guild: "guild_id_2"},
+	}
+
+	for _, tt := range tests {
+		err := a.Do(testNewCtx(ses, tt.author, tt.channel, tt.guild))
+		if tt.kind == "" {
+			if err != nil {
+				t.Errorf("%+v: expected to be allowed, got %v", tt, err)
+			}
+			continue
+		}
+
+		var aclErr *Error
+		if !errors.As(err, &aclErr) {
+			t.Errorf("%+v: expected an acl Error, got %v", tt, err)
+			continue
+		}
+		if aclErr.ACL != "commands" || aclErr.Kind != tt.kind || (aclErr.Rule != nil) != tt.denied {
+			t.Errorf("%+v: unexpected error %+v", tt, aclErr)
+		}
+	}
+
+	// reactions are checked against the user of the reaction
+	reaction := func(userID string) context.Context {
+		return utils.WithReaction(utils.WithSes(context.Background(), ses),
+			&discordgo.MessageReaction{UserID: userID, ChannelID: "bot-commands", GuildID: "guild_id_1"}, false)
+	}
+	if err := a.Do(reaction("mod_id")); err != nil {
+		t.Errorf("expected reaction to be allowed, got %v", err)
+	}
+	if err := a.Do(reaction("muted_id")); err == nil {
+		t.Error("expected reaction of a denied user to be denied")
+	}
+
+	// rules can be changed at runtime
+	_ = store.Remove("commands", "guild_id_1", Rule{Kind: KindChannel, ID: "bot-commands", Effect: Allow})
+	if err := a.Do(testNewCtx(ses, "mod_id", "general", "guild_id_1")); err != nil {
+		t.Errorf("expected removed rule to no longer apply, got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Kind: KindGuild, ID: "guild_id_1"}
+
+	_ = store.Add("a", "", rule)
+	_ = store.Add("a", "", rule)
+	if rules, _ := store.Rules("a", ""); len(rules) != 1 {
+		t.Errorf("expected duplicate rule to be ignored, got %v", rules)
+	}
+	if rules, _ := store.Rules("a", "guild_id_1"); len(rules) != 0 {
+		t.Errorf("expected rules to be scoped to a guild, got %v", rules)
+	}
+
+	_ = store.Remove("a", "", rule)
+	if rules, _ := store.Rules("a", ""); len(rules) != 0 {
+		t.Errorf("expected rule to be removed, got %v", rules)
+	}
+}
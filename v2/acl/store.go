@@ -0,0 +1,75 @@
+package acl
+
+import "sync"
+
+// Store keeps the rules of each ACL, so they can be changed at runtime, such as by commands for guild admins.
+//
+// Rules are scoped to a guild ID. Global rules are stored with an empty guild ID.
+type Store interface {
+	// Rules returns the rules of the ACL name in a guild, in the order they were added.
+	Rules(name, guildID string) ([]Rule, error)
+	// Add adds a rule to the ACL name in a guild. It no-ops if the rule already exists.
+	Add(name, guildID string, rule Rule) error
+	// Remove removes a rule from the ACL name in a guild. It no-ops if the rule does not exist.
+	Remove(name, guildID string, rule Rule) error
+}
+
+type scope struct {
+	name    string
+	guildID string
+}
+
+// MemoryStore is a Store that keeps rules in memory.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	rules map[scope][]Rule
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rules: map[scope][]Rule{}}
+}
+
+// Rules returns a copy of the rules of the ACL name in a guild.
+func (s *MemoryStore) Rules(name, guildID string) ([]Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := s.rules[scope{name: name, guildID: guildID}]
+	return append([]Rule(nil), rules...), nil
+}
+
+// Add adds a rule to the ACL name in a guild.
+func (s *MemoryStore) Add(name, guildID string, rule Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope{name: name, guildID: guildID}
+	for _, r := range s.rules[key] {
+		if r == rule {
+			return nil
+		}
+	}
+	s.rules[key] = append(s.rules[key], rule)
+	return nil
+}
+
+// Remove removes a rule from the ACL name in a guild.
+func (s *MemoryStore) Remove(name, guildID string, rule Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope{name: name, guildID: guildID}
+	rules := s.rules[key][:0:0]
+	for _, r := range s.rules[key] {
+		if r != rule {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		delete(s.rules, key)
+		return nil
+	}
+	s.rules[key] = rules
+	return nil
+}
@@ -0,0 +1,270 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ArgType identifies the Converter used to convert an Arg.
+type ArgType string
+
+// Built-in ArgTypes.
+const (
+	// ArgTypeString keeps the argument as-is.
+	ArgTypeString ArgType = "string"
+	// ArgTypeInt converts to an int.
+	ArgTypeInt ArgType = "int"
+	// ArgTypeFloat converts to a float64.
+	ArgTypeFloat ArgType = "float"
+	// ArgTypeBool converts to a bool. Accepts yes/no and on/off along with values accepted by strconv.ParseBool.
+	ArgTypeBool ArgType = "bool"
+	// ArgTypeDuration converts to a time.Duration with time.ParseDuration.
+	ArgTypeDuration ArgType = "duration"
+	// ArgTypeUser converts a user mention (<@id> or <@!id>) or a snowflake to a user ID.
+	ArgTypeUser ArgType = "user"
+	// ArgTypeChannel converts a channel mention (<#id>) or a snowflake to a channel ID.
+	ArgTypeChannel ArgType = "channel"
+	// ArgTypeRole converts a role mention (<@&id>) or a snowflake to a role ID.
+	ArgTypeRole ArgType = "role"
+	// ArgTypeEmoji converts a custom emoji (<:name:id> or <a:name:id>) to a *discordgo.Emoji.
+	ArgTypeEmoji ArgType = "emoji"
+	// ArgTypeSnowflake validates a Discord snowflake ID and keeps it as a string.
+	ArgTypeSnowflake ArgType = "snowflake"
+)
+
+var (
+	// ErrArgMissing is wrapped by an ArgError when a required argument is not provided.
+	ErrArgMissing = errors.New("missing required argument")
+	// ErrArgsExtra is wrapped by an ArgError when more arguments are provided than declared.
+	ErrArgsExtra = errors.New("too many arguments")
+	// ErrArgTypeUnknown is wrapped by an ArgError when an Arg has an ArgType with no registered Converter.
+	ErrArgTypeUnknown = errors.New("unknown argument type")
+
+	snowflakeRe = regexp.MustCompile(`^[0-9]{17,20}$`)
+	userRe      = regexp.MustCompile(`^<@!?([0-9]{17,20})>$`)
+	channelRe   = regexp.MustCompile(`^<#([0-9]{17,20})>$`)
+	roleRe      = regexp.MustCompile(`^<@&([0-9]{17,20})>$`)
+	emojiRe     = regexp.MustCompile(`^<(a?):([A-Za-z0-9_]{2,32}):([0-9]{17,20})>$`)
+
+	converters = struct {
+		sync.RWMutex
+		m map[ArgType]Converter
+	}{
+		m: map[ArgType]Converter{
+			ArgTypeString:    convertString,
+			ArgTypeInt:       convertInt,
+			ArgTypeFloat:     convertFloat,
+			ArgTypeBool:      convertBool,
+			ArgTypeDuration:  convertDuration,
+			ArgTypeUser:      convertMention(userRe, "user"),
+			ArgTypeChannel:   convertMention(channelRe, "channel"),
+			ArgTypeRole:      convertMention(roleRe, "role"),
+			ArgTypeEmoji:     convertEmoji,
+			ArgTypeSnowflake: convertSnowflake,
+		},
+	}
+)
+
+// Converter converts a raw argument into a typed value.
+// ctx contains the session and message of the invocation.
+type Converter func(ctx context.Context, arg string) (interface{}, error)
+
+// RegisterConverter registers a Converter for an ArgType, replacing any existing Converter for it.
+// It is safe to call concurrently, but is typically called on startup.
+func RegisterConverter(t ArgType, c Converter) {
+	converters.Lock()
+	defer converters.Unlock()
+	converters.m[t] = c
+}
+
+// getConverter returns the Converter registered to an ArgType.
+func getConverter(t ArgType) (Converter, bool) {
+	converters.RLock()
+	defer converters.RUnlock()
+	c, ok := converters.m[t]
+	return c, ok
+}
+
+// Arg declares a positional argument of a Route.
+type Arg struct {
+	Name string
+	// Type of the argument. If empty, defaults to ArgTypeString.
+	Type ArgType
+	// Optional arguments may be omitted. Only trailing arguments may be optional.
+	Optional bool
+	// Variadic arguments consume all remaining arguments, and are converted into a []interface{}.
+	// Only the last argument may be variadic. A variadic argument is required unless Optional is also set.
+	Variadic bool
+	// Default is the value of an Optional argument when it is omitted. If nil, the argument will have no value.
+	Default interface{}
+}
+
+// validateArgs returns an error if the declaration of args is ambiguous:
+// a variadic argument must be the last argument, and a required argument cannot follow an optional argument.
+func validateArgs(args []Arg) error {
+	for i, a := range args {
+		if a.Variadic && i != len(args)-1 {
+			return fmt.Errorf("variadic argument '%s' must be the last argument", a.Name)
+		}
+		if i > 0 && args[i-1].Optional && !a.Optional {
+			return fmt.Errorf("required argument '%s' cannot follow optional argument '%s'", a.Name, args[i-1].Name)
+		}
+	}
+	return nil
+}
+
+// ArgError is returned when arguments do not satisfy the Args declared on a Route.
+type ArgError struct {
+	Name  string // name of the declared argument, empty if there were extra arguments
+	Value string // raw value of the argument, empty if it was missing
+	Err   error
+}
+
+func (e *ArgError) Error() string {
+	switch {
+	case e.Name == "":
+		return fmt.Sprintf("%v: '%s'", e.Err, e.Value)
+	case errors.Is(e.Err, ErrArgMissing):
+		return fmt.Sprintf("argument '%s': %v", e.Name, e.Err)
+	default:
+		return fmt.Sprintf("argument '%s' ('%s'): %v", e.Name, e.Value, e.Err)
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *ArgError) Unwrap() error {
+	return e.Err
+}
+
+// convertArgs converts raw arguments into values keyed by the name of their declared Arg.
+func convertArgs(ctx context.Context, decl []Arg, args []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(decl))
+
+	for i, a := range decl {
+		c, ok := getConverter(a.Type)
+		if a.Type == "" {
+			c, ok = convertString, true
+		}
+		if !ok {
+			return nil, &ArgError{Name: a.Name, Err: fmt.Errorf("%w '%s'", ErrArgTypeUnknown, a.Type)}
+		}
+
+		if i >= len(args) {
+			if !a.Optional {
+				return nil, &ArgError{Name: a.Name, Err: ErrArgMissing}
+			}
+			if a.Default != nil {
+				values[a.Name] = a.Default
+			}
+			continue
+		}
+
+		raw := args[i : i+1]
+		if a.Variadic {
+			raw = args[i:]
+		}
+
+		converted := make([]interface{}, 0, len(raw))
+		for _, arg := range raw {
+			v, err := c(ctx, arg)
+			if err != nil {
+				return nil, &ArgError{Name: a.Name, Value: arg, Err: err}
+			}
+			converted = append(converted, v)
+		}
+
+		if a.Variadic {
+			values[a.Name] = converted
+			return values, nil
+		}
+		values[a.Name] = converted[0]
+	}
+
+	if len(args) > len(decl) {
+		return nil, &ArgError{Value: strings.Join(args[len(decl):], " "), Err: ErrArgsExtra}
+	}
+
+	return values, nil
+}
+
+func convertString(_ context.Context, arg string) (interface{}, error) {
+	return arg, nil
+}
+
+func convertInt(_ context.Context, arg string) (interface{}, error) {
+	i, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, errors.New("not an integer")
+	}
+	return i, nil
+}
+
+func convertFloat(_ context.Context, arg string) (interface{}, error) {
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return nil, errors.New("not a number")
+	}
+	return f, nil
+}
+
+func convertBool(_ context.Context, arg string) (interface{}, error) {
+	switch strings.ToLower(arg) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(arg)
+	if err != nil {
+		return nil, errors.New("not a boolean")
+	}
+	return b, nil
+}
+
+func convertDuration(_ context.Context, arg string) (interface{}, error) {
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		return nil, errors.New("not a duration")
+	}
+	return d, nil
+}
+
+func convertSnowflake(_ context.Context, arg string) (interface{}, error) {
+	if !snowflakeRe.MatchString(arg) {
+		return nil, errors.New("not an ID")
+	}
+	return arg, nil
+}
+
+// convertMention returns a Converter that extracts the ID of a mention matching re, or accepts a snowflake.
+func convertMention(re *regexp.Regexp, kind string) Converter {
+	return func(_ context.Context, arg string) (interface{}, error) {
+		if m := re.FindStringSubmatch(arg); m != nil {
+			return m[1], nil
+		}
+		if snowflakeRe.MatchString(arg) {
+			return arg, nil
+		}
+		return nil, fmt.Errorf("not a %s mention or ID", kind)
+	}
+}
+
+func convertEmoji(_ context.Context, arg string) (interface{}, error) {
+	m := emojiRe.FindStringSubmatch(arg)
+	if m == nil {
+		return nil, errors.New("not a custom emoji")
+	}
+	return &discordgo.Emoji{
+		ID:       m[3],
+		Name:     m[2],
+		Animated: m[1] == "a",
+	}, nil
+}
@@ -0,0 +1,152 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertArgs(t *testing.T) {
+	decl := []Arg{
+		{Name: "count", Type: ArgTypeInt},
+		{Name: "user", Type: ArgTypeUser},
+		{Name: "within", Type: ArgTypeDuration, Optional: true, Default: time.Hour},
+		{Name: "channels", Type: ArgTypeChannel, Optional: true, Variadic: true},
+	}
+
+	testCases := []struct {
+		name     string
+		args     string
+		expected map[string]interface{}
+		errName  string // name of the argument the ArgError should contain
+		err      error  // error wrapped by the ArgError
+	}{
+		{
+			name:     "defaults are used for omitted optional arguments",
+			args:     "50 <@!80351110224678912>",
+			expected: map[string]interface{}{"count": 50, "user": "80351110224678912", "within": time.Hour},
+		},
+		{
+			name: "variadic arguments consume the remaining arguments",
+			args: "50 80351110224678912 30m <#381870553235193857> 381871767846780928",
+			expected: map[string]interface{}{
+				"count": 50, "user": "80351110224678912", "within": 30 * time.Minute, "channels": []interface{}{"381870553235193857", "381871767846780928"},
+			},
+		},
+		{name: "missing required argument", args: "50", errName: "user", err: ErrArgMissing},
+		{name: "bad integer", args: "fifty <@80351110224678912>", errName: "count"},
+		{name: "bad mention", args: "50 <#80351110224678912>", errName: "user"},
+		{name: "short ID", args: "50 1234567890123456", errName: "user"},
+		{name: "long ID", args: "50 <@123456789012345678901>", errName: "user"},
+		{name: "bad variadic argument", args: "50 <@80351110224678912> 1m <#381870553235193857> <@381871767846780928>", errName: "channels"},
+	}
+
+	for _, c := range testCases {
+		values, err := convertArgs(context.Background(), decl, strings.Fields(c.args))
+
+		if c.errName == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(c.expected, values) {
+				t.Errorf("%s: expected values %v, got %v", c.name, c.expected, values)
+			}
+			continue
+		}
+
+		var argErr *ArgError
+		if !errors.As(err, &argErr) {
+			t.Errorf("%s: expected an *ArgError, got %v", c.name, err)
+			continue
+		}
+		if argErr.Name != c.errName {
+			t.Errorf("%s: expected error for argument %s, got %s", c.name, c.errName, argErr.Name)
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("%s: expected error to wrap %v, got %v", c.name, c.err, err)
+		}
+	}
+
+	_, err := convertArgs(context.Background(), []Arg{{Name: "a"}}, []string{"x", "y"})
+	if !errors.Is(err, ErrArgsExtra) {
+		t.Errorf("expected extra arguments to return %v, got %v", ErrArgsExtra, err)
+	}
+}
+
+func TestRoute_Args(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		cmd    *CmdContext
+	)
+
+	RegisterConverter("upper", func(_ context.Context, arg string) (interface{}, error) {
+		return strings.ToUpper(arg), nil
+	})
+
+	router.Has(NewRoute(&testPref{}).On("purge").Args(
+		Arg{Name: "count", Type: ArgTypeInt},
+		Arg{Name: "emoji", Type: ArgTypeEmoji},
+		Arg{Name: "reason", Type: "upper", Optional: true},
+	).Do(&testCmd{
+		HandleCallback: func(ctx context.Context) error {
+			cmd = CmdFromContext(ctx)
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			if cmd == nil {
+				cmd = CmdFromContext(ctx)
+			}
+		},
+	}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"purge 5 <a:party:397846423389519872> spam"))
+	if cmd == nil || cmd.Err != nil {
+		t.Fatalf("expected handler to run without error, got %v", cmd)
+	}
+	if cmd.IntArg("count") != 5 || cmd.StringArg("reason") != "SPAM" {
+		t.Errorf("unexpected converted values %v", cmd.Values)
+	}
+	if e := cmd.EmojiArg("emoji"); e == nil || e.ID != "397846423389519872" || e.Name != "party" || !e.Animated {
+		t.Errorf("unexpected emoji %v", e)
+	}
+
+	cmd = nil
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"purge 5 :party:"))
+	var argErr *ArgError
+	if cmd == nil || !errors.As(cmd.Err, &argErr) || argErr.Name != "emoji" {
+		t.Errorf("expected an *ArgError for emoji to be resolved, got %v", cmd)
+	}
+}
+
+func TestRoute_Args_invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		args [][]Arg // arguments of each call to Args
+	}{
+		{name: "variadic argument before another", args: [][]Arg{{{Name: "a", Variadic: true}, {Name: "b"}}}},
+		{name: "variadic argument before another call", args: [][]Arg{{{Name: "a", Variadic: true}}, {{Name: "b"}}}},
+		{name: "required argument after optional", args: [][]Arg{{{Name: "a", Optional: true}, {Name: "b"}}}},
+		{name: "required variadic argument after optional", args: [][]Arg{{{Name: "a", Optional: true}, {Name: "b", Variadic: true}}}},
+	}
+
+	for _, c := range testCases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected Args to panic", c.name)
+				}
+			}()
+			route := NewRoute(nil)
+			for _, args := range c.args {
+				route.Args(args...)
+			}
+		}()
+	}
+
+	NewRoute(nil).Args(Arg{Name: "a"}, Arg{Name: "b", Optional: true}).Args(Arg{Name: "c", Optional: true, Variadic: true})
+}
@@ -0,0 +1,180 @@
+package v2
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pixeltopic/sayori/v2/filter"
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// ErrRouterClosed is returned by Await and Paginator.Run when the Router is closed before or while waiting.
+	ErrRouterClosed = errors.New("router closed")
+	// ErrNoRouter is returned by AwaitReply and Paginator.Run when the Context was not created by a Router.
+	ErrNoRouter = errors.New("context has no router")
+	// ErrNoMessage is returned by AwaitReply and Paginator.Run when the Context has no message to reply to.
+	ErrNoMessage = errors.New("context has no message")
+	// ErrAwaitDeadlock is returned by Await, Confirm.Ask and Paginator.Run when called from a handler that blocks
+	// the session from receiving events, so the awaited event could never be received. Handlers block the session
+	// if Session.SyncEvents is set, unless they run on a worker pool with the OverflowDrop or OverflowBusy policy.
+	ErrAwaitDeadlock = errors.New("awaiting would deadlock, the handler blocks the session from receiving events")
+)
+
+// waiter receives events of the Router that match a condition, for Await or components such as Paginator.
+type waiter struct {
+	match  func(ctx context.Context) bool
+	events chan context.Context // receives the Context of each matching event, dropped if the buffer is full
+	closed chan struct{}        // closed when the Router is closed
+	once   bool                 // if true, the waiter is removed after its first event
+}
+
+// Await waits for the next message received by the Router that p holds for, and returns it.
+// p is evaluated with a Context containing the session and the message, and may be nil to accept any message.
+//
+// Await returns ctx.Err() if ctx is done first, such as when its deadline passes,
+// and ErrRouterClosed if the Router is closed first or was already closed. Messages are still handled by routes
+// after they are awaited.
+//
+// Edited messages are not awaited. Await blocks the calling handler, so it returns ErrAwaitDeadlock if the handler
+// of ctx blocks the session from receiving events.
+func (r *Router) Await(ctx context.Context, p filter.Predicate) (*discordgo.Message, error) {
+	if err := r.awaitable(ctx); err != nil {
+		return nil, err
+	}
+	w, err := r.subscribe(func(ctx context.Context) bool {
+		return utils.GetMsg(ctx) != nil && (p == nil || p.Eval(ctx) == nil)
+	}, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	defer r.unsubscribe(w)
+
+	select {
+	case ectx := <-w.events:
+		return utils.GetMsg(ectx), nil
+	case <-w.closed:
+		return nil, ErrRouterClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AwaitReply waits for the next message from the author of the message in ctx, in the same channel, that p holds for.
+// p may be nil to accept any message of the author. It is meant to be called from Handle to ask follow-up questions,
+// and returns ErrNoRouter if ctx was not created by a Router.
+//
+// For example, to wait 30 seconds for the author to reply:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+//	defer cancel()
+//	reply, err := sayori.AwaitReply(ctx, nil)
+//
+// See Router.Await for the errors returned.
+func AwaitReply(ctx context.Context, p filter.Predicate) (*discordgo.Message, error) {
+	r := RouterFromContext(ctx)
+	if r == nil {
+		return nil, ErrNoRouter
+	}
+	msg := utils.GetMsg(ctx)
+	if msg == nil || msg.Author == nil {
+		return nil, ErrNoMessage
+	}
+
+	reply := filter.And(filter.AuthorIs(msg.Author.ID), filter.ChannelIs(msg.ChannelID))
+	if p != nil {
+		reply = filter.And(reply, p)
+	}
+	return r.Await(ctx, reply)
+}
+
+// awaitable returns an error if events awaited by the handler of ctx could never be received.
+func (r *Router) awaitable(ctx context.Context) error {
+	if eventsBlocked(ctx) {
+		return ErrAwaitDeadlock
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return ErrRouterClosed
+	}
+	return nil
+}
+
+// subscribe registers a waiter receiving the Context of messages and reactions match holds for,
+// adding the handlers of the Router to the session if they were not already added.
+//
+// The waiter buffers up to size events. If once is true, it is removed after its first event.
+// Returns ErrRouterClosed if the Router is closed.
+func (r *Router) subscribe(match func(ctx context.Context) bool, size int, once bool) (*waiter, error) {
+	// r.mu is held while the waiter is added, so it is either rejected here or closed by Close
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrRouterClosed
+	}
+	r.ensureRegistered()
+
+	w := &waiter{
+		match:  match,
+		events: make(chan context.Context, size),
+		closed: make(chan struct{}),
+		once:   once,
+	}
+
+	r.waitMu.Lock()
+	defer r.waitMu.Unlock()
+	if r.waiters == nil {
+		r.waiters = map[*waiter]struct{}{}
+	}
+	r.waiters[w] = struct{}{}
+	return w, nil
+}
+
+// unsubscribe unregisters a waiter. No-ops if it was already removed.
+func (r *Router) unsubscribe(w *waiter) {
+	r.waitMu.Lock()
+	defer r.waitMu.Unlock()
+	delete(r.waiters, w)
+}
+
+// notifyWaiters passes the Context of a message or reaction to every waiter whose condition holds for it.
+func (r *Router) notifyWaiters(ctx context.Context) {
+	r.waitMu.Lock()
+	if len(r.waiters) == 0 {
+		r.waitMu.Unlock()
+		return
+	}
+	waiters := make([]*waiter, 0, len(r.waiters))
+	for w := range r.waiters {
+		waiters = append(waiters, w)
+	}
+	r.waitMu.Unlock()
+
+	for _, w := range waiters {
+		if !w.match(ctx) {
+			continue
+		}
+		select {
+		case w.events <- ctx:
+			if w.once {
+				r.unsubscribe(w)
+			}
+		default: // buffer is full
+		}
+	}
+}
+
+// closeWaiters stops every pending Await with ErrRouterClosed, and signals other waiters the Router was closed.
+func (r *Router) closeWaiters() {
+	r.waitMu.Lock()
+	defer r.waitMu.Unlock()
+
+	for w := range r.waiters {
+		close(w.closed)
+	}
+	r.waiters = nil
+}
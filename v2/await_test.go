@@ -0,0 +1,145 @@
+package v2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/filter"
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+func TestAwaitReply(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		msg    = makeMockMsg("what's the event name?").Message
+		ctx    = withRouter(utils.WithSes(utils.WithMsg(context.Background(), msg), ses), router)
+	)
+	msg.ChannelID = "channel_id_1"
+
+	reply := func(authorID, channelID, content string) {
+		m := makeMockMsg(content)
+		m.Author.ID = authorID
+		m.ChannelID = channelID
+		router.onMessageCreate(ses, m)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for router.waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		reply("author_id_2", "channel_id_1", "not the author")
+		reply("author_id_1", "channel_id_2", "another channel")
+		reply("author_id_1", "channel_id_1", "")
+		reply("author_id_1", "channel_id_1", "launch party")
+	}()
+
+	got, err := AwaitReply(ctx, filter.Not(filter.NoContent))
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "launch party" {
+		t.Errorf("expected reply 'launch party', got %q", got.Content)
+	}
+	if n := router.waiting(); n != 0 {
+		t.Errorf("expected waiter to be removed, got %d", n)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := AwaitReply(tctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline to be exceeded, got %v", err)
+	}
+	if n := router.waiting(); n != 0 {
+		t.Errorf("expected waiter to be removed after timeout, got %d", n)
+	}
+
+	if _, err := AwaitReply(context.Background(), nil); err != ErrNoRouter {
+		t.Errorf("expected ErrNoRouter, got %v", err)
+	}
+}
+
+func TestRouter_Await_close(t *testing.T) {
+	router := New(makeMockSes())
+
+	go func() {
+		for router.waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		_ = router.Close()
+	}()
+
+	if _, err := router.Await(context.Background(), nil); err != ErrRouterClosed {
+		t.Errorf("expected ErrRouterClosed, got %v", err)
+	}
+
+	if _, err := router.Await(context.Background(), nil); err != ErrRouterClosed {
+		t.Errorf("expected ErrRouterClosed once the Router is closed, got %v", err)
+	}
+	if n := router.waiting(); n != 0 {
+		t.Errorf("expected no waiter to be registered on a closed Router, got %d", n)
+	}
+
+	router.reopen()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := router.Await(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected a reopened Router to be awaited, got %v", err)
+	}
+}
+
+func TestRouter_Await_deadlock(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		errs   = make(chan error, 1)
+	)
+	ses.SyncEvents = true
+	defer router.Close()
+
+	router.Has(NewRoute(&testPref{}).On("ask").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := AwaitReply(ctx, nil)
+		errs <- err
+		return nil
+	}}))
+
+	testCases := []struct {
+		name     string
+		workers  *WorkerOptions
+		expected error
+	}{
+		{name: "synchronous", expected: ErrAwaitDeadlock},
+		{name: "blocking pool", workers: &WorkerOptions{Queue: 1, Overflow: OverflowBlock}, expected: ErrAwaitDeadlock},
+		{name: "dropping pool", workers: &WorkerOptions{Queue: 1, Overflow: OverflowDrop}, expected: context.DeadlineExceeded},
+		{name: "busy pool", workers: &WorkerOptions{Queue: 1, Overflow: OverflowBusy}, expected: context.DeadlineExceeded},
+	}
+
+	for _, c := range testCases {
+		if c.workers != nil {
+			router.Workers(*c.workers)
+		}
+		router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"ask"))
+
+		select {
+		case err := <-errs:
+			if err != c.expected {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: expected the handler to return", c.name)
+		}
+	}
+}
+
+// waiting returns the number of registered waiters.
+func (r *Router) waiting() int {
+	r.waitMu.Lock()
+	defer r.waitMu.Unlock()
+	return len(r.waiters)
+}
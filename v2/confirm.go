@@ -0,0 +1,183 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ConfirmResult is the answer to a Confirm prompt.
+type ConfirmResult int
+
+const (
+	// TimedOut means the prompt was not answered in time.
+	TimedOut ConfirmResult = iota
+	// Confirmed means the prompt was answered with yes.
+	Confirmed
+	// Denied means the prompt was answered with no.
+	Denied
+)
+
+func (r ConfirmResult) String() string {
+	switch r {
+	case Confirmed:
+		return "confirmed"
+	case Denied:
+		return "denied"
+	default:
+		return "timed out"
+	}
+}
+
+var (
+	// ErrDenied is passed to Resolve when the confirmation of a Route is denied.
+	ErrDenied = errors.New("command was cancelled")
+	// ErrConfirmTimeout is passed to Resolve when the confirmation of a Route is not answered in time.
+	ErrConfirmTimeout = errors.New("confirmation timed out")
+)
+
+// Confirm asks the author of a message to confirm an action, such as "are you sure? react ✅/❌ or type yes/no",
+// before destructive commands.
+//
+// The author answers by adding one of the emojis to the prompt, or by replying in the channel with one of the words.
+// Words are matched case-insensitively. The emojis and words can be changed before the prompt is asked.
+type Confirm struct {
+	Prompt  string
+	Timeout time.Duration
+
+	Yes, No           string   // emojis added to the prompt
+	YesWords, NoWords []string // replies accepted as answers
+}
+
+// NewConfirm returns a Confirm with the given prompt, a timeout of 30 seconds, the emojis ✅ and ❌,
+// and the words yes/y and no/n.
+func NewConfirm(prompt string) *Confirm {
+	return &Confirm{
+		Prompt:   prompt,
+		Timeout:  30 * time.Second,
+		Yes:      "✅",
+		No:       "❌",
+		YesWords: []string{"yes", "y"},
+		NoWords:  []string{"no", "n"},
+	}
+}
+
+// Ask sends the prompt in the channel of the message in ctx and waits for the author to answer, or for Timeout.
+// The emojis are removed from the prompt once it is answered.
+//
+// Ask returns TimedOut with an error if ctx is done or the Router is closed before the prompt is answered,
+// ErrNoRouter if ctx was not created by a Router, and ErrAwaitDeadlock if the handler of ctx blocks the session
+// from receiving events.
+func (c *Confirm) Ask(ctx context.Context) (ConfirmResult, error) {
+	r := RouterFromContext(ctx)
+	if r == nil {
+		return TimedOut, ErrNoRouter
+	}
+	ses, msg := utils.GetSes(ctx), utils.GetMsg(ctx)
+	if msg == nil || msg.Author == nil {
+		return TimedOut, ErrNoMessage
+	}
+	if err := r.awaitable(ctx); err != nil {
+		return TimedOut, err
+	}
+
+	prompt, err := ses.ChannelMessageSend(msg.ChannelID, c.Prompt)
+	if err != nil {
+		return TimedOut, err
+	}
+
+	w, err := r.subscribe(func(ectx context.Context) bool {
+		_, ok := c.answer(ectx, msg, prompt.ID)
+		return ok
+	}, 1, true)
+	if err != nil {
+		return TimedOut, err
+	}
+	defer r.unsubscribe(w)
+	defer func() { _ = ses.MessageReactionsRemoveAll(prompt.ChannelID, prompt.ID) }()
+
+	for _, emoji := range []string{c.Yes, c.No} {
+		if emoji == "" {
+			continue
+		}
+		if err := ses.MessageReactionAdd(prompt.ChannelID, prompt.ID, emoji); err != nil {
+			return TimedOut, err
+		}
+	}
+
+	timeout := time.NewTimer(c.Timeout)
+	defer timeout.Stop()
+
+	select {
+	case ectx := <-w.events:
+		result, _ := c.answer(ectx, msg, prompt.ID)
+		return result, nil
+	case <-timeout.C:
+		return TimedOut, nil
+	case <-w.closed:
+		return TimedOut, ErrRouterClosed
+	case <-ctx.Done():
+		return TimedOut, ctx.Err()
+	}
+}
+
+// answer returns the answer of the event in ctx to the prompt with the given ID, which was sent in reply to msg.
+// Returns false if the event is not an answer.
+func (c *Confirm) answer(ctx context.Context, msg *discordgo.Message, promptID string) (ConfirmResult, bool) {
+	if reaction := utils.GetReaction(ctx); reaction != nil {
+		if utils.IsReactionRemoved(ctx) || reaction.MessageID != promptID || reaction.UserID != msg.Author.ID {
+			return TimedOut, false
+		}
+		switch {
+		case c.Yes != "" && matchesEmoji(c.Yes, reaction.Emoji):
+			return Confirmed, true
+		case c.No != "" && matchesEmoji(c.No, reaction.Emoji):
+			return Denied, true
+		}
+		return TimedOut, false
+	}
+
+	reply := utils.GetMsg(ctx)
+	if reply == nil || reply.Author == nil || reply.Author.ID != msg.Author.ID || reply.ChannelID != msg.ChannelID {
+		return TimedOut, false
+	}
+	content := strings.TrimSpace(reply.Content)
+	switch {
+	case containsFold(c.YesWords, content):
+		return Confirmed, true
+	case containsFold(c.NoWords, content):
+		return Denied, true
+	}
+	return TimedOut, false
+}
+
+// containsFold reports if words contains s, ignoring case.
+func containsFold(words []string, s string) bool {
+	for _, w := range words {
+		if strings.EqualFold(w, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks c before a route is handled, returning ErrDenied or ErrConfirmTimeout if it is not confirmed.
+func confirm(ctx context.Context, c *Confirm) error {
+	result, err := c.Ask(ctx)
+	if err != nil {
+		return err
+	}
+	switch result {
+	case Confirmed:
+		return nil
+	case Denied:
+		return ErrDenied
+	default:
+		return ErrConfirmTimeout
+	}
+}
@@ -0,0 +1,152 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestConfirm_answer(t *testing.T) {
+	var (
+		c   = NewConfirm("are you sure?")
+		msg = makeMockMsg("!purge").Message
+	)
+	msg.ChannelID = "channel_id_1"
+
+	reply := func(authorID, content string) context.Context {
+		m := makeMockMsg(content).Message
+		m.Author.ID = authorID
+		m.ChannelID = "channel_id_1"
+		return utils.WithMsg(context.Background(), m)
+	}
+	react := func(userID, messageID, emoji string, removed bool) context.Context {
+		return utils.WithReaction(context.Background(), makeMockReaction(userID, messageID, discordgo.Emoji{Name: emoji}), removed)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected ConfirmResult
+		ok       bool
+	}{
+		{name: "yes reaction", ctx: react("author_id_1", "prompt_id_1", "✅", false), expected: Confirmed, ok: true},
+		{name: "no reaction", ctx: react("author_id_1", "prompt_id_1", "❌", false), expected: Denied, ok: true},
+		{name: "removed reaction", ctx: react("author_id_1", "prompt_id_1", "✅", true)},
+		{name: "other user reaction", ctx: react("author_id_2", "prompt_id_1", "✅", false)},
+		{name: "other message reaction", ctx: react("author_id_1", "message_id_1", "✅", false)},
+		{name: "other emoji", ctx: react("author_id_1", "prompt_id_1", "👍", false)},
+		{name: "yes reply", ctx: reply("author_id_1", " YES "), expected: Confirmed, ok: true},
+		{name: "no reply", ctx: reply("author_id_1", "n"), expected: Denied, ok: true},
+		{name: "other reply", ctx: reply("author_id_1", "maybe")},
+		{name: "other user reply", ctx: reply("author_id_2", "yes")},
+	}
+
+	for _, tt := range tests {
+		result, ok := c.answer(tt.ctx, msg, "prompt_id_1")
+		if result != tt.expected || ok != tt.ok {
+			t.Errorf("%s: expected %s (ok=%v), got %s (ok=%v)", tt.name, tt.expected, tt.ok, result, ok)
+		}
+	}
+}
+
+func TestRoute_Confirm(t *testing.T) {
+	prompted := make(chan struct{}, 1)
+	ses, srv := makeMockRESTSes(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost: // the prompt
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "prompt_id_1", "channel_id": "channel_id_1"}`))
+		case http.MethodPut: // the emojis are added once the prompt listens for answers
+			if strings.Contains(req.URL.Path, "✅") {
+				prompted <- struct{}{}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer srv.Close()
+
+	var (
+		router  = New(ses)
+		c       = NewConfirm("are you sure?")
+		handled bool
+		err     error
+	)
+	c.Timeout = 50 * time.Millisecond
+
+	router.Has(NewRoute(&testPref{}).On("purge").Do(&testCmd{
+		HandleCallback: func(ctx context.Context) error {
+			handled = true
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			err = utils.GetErr(ctx)
+		},
+	}).Confirm(c))
+
+	message := func(content string) *discordgo.MessageCreate {
+		m := makeMockMsg(content)
+		m.ChannelID = "channel_id_1"
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		answer  func()
+		handled bool
+		err     error
+	}{
+		{
+			name:    "yes reply",
+			answer:  func() { router.onMessageCreate(ses, message("yes")) },
+			handled: true,
+		},
+		{
+			name: "no reaction",
+			answer: func() {
+				reaction := makeMockReaction("author_id_1", "prompt_id_1", discordgo.Emoji{Name: "❌"})
+				router.onMessageReactionAdd(ses, &discordgo.MessageReactionAdd{MessageReaction: reaction})
+			},
+			err: ErrDenied,
+		},
+		{
+			name: "timeout",
+			err:  ErrConfirmTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		handled, err = false, nil
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			router.onMessageCreate(ses, message(testDefaultPrefix+"purge"))
+		}()
+
+		select {
+		case <-prompted:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: expected the prompt to be sent", tt.name)
+		}
+		if tt.answer != nil {
+			tt.answer()
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: expected the route to return once the prompt is answered", tt.name)
+		}
+		if handled != tt.handled || err != tt.err {
+			t.Errorf("%s: expected handled=%v and error %v, got handled=%v and error %v", tt.name, tt.handled, tt.err, handled, err)
+		}
+	}
+}
@@ -2,12 +2,45 @@ package v2
 
 import (
 	"context"
+	"time"
 
 	"github.com/pixeltopic/sayori/v2/utils"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+type ctxKey int
+
+const (
+	ctxRouterKey ctxKey = iota
+	ctxBlockingKey
+)
+
+// withRouter attaches the Router handling an event to Context.
+func withRouter(ctx context.Context, r *Router) context.Context {
+	return context.WithValue(ctx, ctxRouterKey, r)
+}
+
+// withBlocking marks Context as handled by a handler that blocks the session from receiving events until it returns.
+func withBlocking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxBlockingKey, true)
+}
+
+// eventsBlocked reports if the handler of the event in Context blocks the session from receiving events until it returns.
+func eventsBlocked(ctx context.Context) bool {
+	blocking, _ := ctx.Value(ctxBlockingKey).(bool)
+	return blocking
+}
+
+// RouterFromContext returns the Router handling the event in Context. If Router not present, returns nil.
+func RouterFromContext(ctx context.Context) *Router {
+	r, ok := ctx.Value(ctxRouterKey).(*Router)
+	if !ok {
+		return nil
+	}
+	return r
+}
+
 // CmdContext is an aux structure for storing invocation values extracted from a given Context to reduce boilerplate.
 //
 // This need not be manually initialized; simply call CmdFromContext.
@@ -17,7 +50,11 @@ type CmdContext struct {
 	Prefix string
 	Alias  []string
 	Args   []string
+	Values map[string]interface{} // Args converted according to the Args declared on the Route
+	Flags  utils.Flags
 	Err    error
+	Edited bool               // true if Msg was edited rather than newly sent
+	Before *discordgo.Message // Msg before it was edited, if known
 }
 
 // CmdFromContext derives all Command invocation values from given Context.
@@ -28,6 +65,78 @@ func CmdFromContext(ctx context.Context) *CmdContext {
 		Prefix: utils.GetPrefix(ctx),
 		Alias:  utils.GetAlias(ctx),
 		Args:   utils.GetArgs(ctx),
+		Values: utils.GetArgValues(ctx),
+		Flags:  utils.GetFlags(ctx),
 		Err:    utils.GetErr(ctx),
+		Edited: utils.IsEdit(ctx),
+		Before: utils.GetBeforeEdit(ctx),
 	}
 }
+
+// Arg returns the converted value of a declared argument, and false if it has no value.
+func (c *CmdContext) Arg(name string) (interface{}, bool) {
+	v, ok := c.Values[name]
+	return v, ok
+}
+
+// ArgList returns the converted values of a variadic argument.
+func (c *CmdContext) ArgList(name string) []interface{} {
+	v, _ := c.Values[name].([]interface{})
+	return v
+}
+
+// StringArg returns the value of an ArgTypeString argument, or an empty string if it has no value.
+func (c *CmdContext) StringArg(name string) string {
+	v, _ := c.Values[name].(string)
+	return v
+}
+
+// IntArg returns the value of an ArgTypeInt argument, or zero if it has no value.
+func (c *CmdContext) IntArg(name string) int {
+	v, _ := c.Values[name].(int)
+	return v
+}
+
+// FloatArg returns the value of an ArgTypeFloat argument, or zero if it has no value.
+func (c *CmdContext) FloatArg(name string) float64 {
+	v, _ := c.Values[name].(float64)
+	return v
+}
+
+// BoolArg returns the value of an ArgTypeBool argument, or false if it has no value.
+func (c *CmdContext) BoolArg(name string) bool {
+	v, _ := c.Values[name].(bool)
+	return v
+}
+
+// DurationArg returns the value of an ArgTypeDuration argument, or zero if it has no value.
+func (c *CmdContext) DurationArg(name string) time.Duration {
+	v, _ := c.Values[name].(time.Duration)
+	return v
+}
+
+// UserArg returns the user ID of an ArgTypeUser argument, or an empty string if it has no value.
+func (c *CmdContext) UserArg(name string) string {
+	return c.StringArg(name)
+}
+
+// ChannelArg returns the channel ID of an ArgTypeChannel argument, or an empty string if it has no value.
+func (c *CmdContext) ChannelArg(name string) string {
+	return c.StringArg(name)
+}
+
+// RoleArg returns the role ID of an ArgTypeRole argument, or an empty string if it has no value.
+func (c *CmdContext) RoleArg(name string) string {
+	return c.StringArg(name)
+}
+
+// SnowflakeArg returns the ID of an ArgTypeSnowflake argument, or an empty string if it has no value.
+func (c *CmdContext) SnowflakeArg(name string) string {
+	return c.StringArg(name)
+}
+
+// EmojiArg returns the value of an ArgTypeEmoji argument, or nil if it has no value.
+func (c *CmdContext) EmojiArg(name string) *discordgo.Emoji {
+	v, _ := c.Values[name].(*discordgo.Emoji)
+	return v
+}
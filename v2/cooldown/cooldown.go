@@ -0,0 +1,160 @@
+// Package cooldown provides a middleware that rate limits routes.
+package cooldown
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Clock provides the current time. It can be replaced to test cooldowns deterministically.
+	Clock interface {
+		Now() time.Time
+	}
+
+	// KeyFunc maps a message to the key of the bucket it uses.
+	KeyFunc func(m *discordgo.Message) string
+
+	// Cooldown is a Middlewarer that limits how often messages sharing a bucket key can invoke a route.
+	//
+	// The zero value is not usable; call New.
+	Cooldown struct {
+		key       KeyFunc
+		strategy  Strategy
+		store     Store
+		clock     Clock
+		namespace string
+		init      sync.Once
+	}
+
+	// Error is returned by Cooldown when a bucket is exhausted.
+	Error struct {
+		Key  string        // key of the exhausted bucket
+		Wait time.Duration // time until the bucket can be used again
+	}
+
+	systemClock struct{}
+)
+
+// Now returns time.Now.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock used by default.
+var SystemClock Clock = systemClock{}
+
+// PerUser shares a bucket between messages from the same user.
+func PerUser(m *discordgo.Message) string {
+	if m.Author == nil {
+		return "user:"
+	}
+	return "user:" + m.Author.ID
+}
+
+// PerChannel shares a bucket between messages in the same channel.
+func PerChannel(m *discordgo.Message) string {
+	return "channel:" + m.ChannelID
+}
+
+// PerGuild shares a bucket between messages in the same guild.
+// Direct messages have no guild, so they share a bucket with messages in the same channel instead.
+func PerGuild(m *discordgo.Message) string {
+	if m.GuildID == "" {
+		return PerChannel(m)
+	}
+	return "guild:" + m.GuildID
+}
+
+// Global shares a single bucket between all messages.
+func Global(_ *discordgo.Message) string {
+	return "global"
+}
+
+// Remaining returns the wait rounded up to the nearest second, suitable to show to users.
+func (e *Error) Remaining() time.Duration {
+	return (e.Wait + time.Second - 1) / time.Second * time.Second
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("on cooldown, try again in %v", e.Remaining())
+}
+
+// New returns a Cooldown using key to select buckets and strategy to limit them.
+//
+// By default, time is read from SystemClock and buckets are stored in a new MemoryStore using the same Clock.
+func New(key KeyFunc, strategy Strategy) *Cooldown {
+	return &Cooldown{
+		key:      key,
+		strategy: strategy,
+		clock:    SystemClock,
+	}
+}
+
+// WithStore sets the Store buckets are kept in.
+// Set a Namespace if the Store is shared between Cooldowns.
+func (c *Cooldown) WithStore(s Store) *Cooldown {
+	c.store = s
+	return c
+}
+
+// WithClock sets the Clock used to read the current time.
+func (c *Cooldown) WithClock(clock Clock) *Cooldown {
+	c.clock = clock
+	return c
+}
+
+// Namespace prefixes all bucket keys so that Cooldowns sharing a Store do not share buckets.
+func (c *Cooldown) Namespace(ns string) *Cooldown {
+	c.namespace = ns
+	return c
+}
+
+// Do takes from the bucket of the message, returning an *Error if it is exhausted.
+//
+// If the Context holds a reaction instead of a message, such as in the reaction middlewares of a Router,
+// the bucket is keyed by the user, channel and guild of the reaction.
+func (c *Cooldown) Do(ctx context.Context) error {
+	msg := utils.GetMsg(ctx)
+	if reaction := utils.GetReaction(ctx); msg == nil && reaction != nil {
+		msg = &discordgo.Message{
+			Author:    &discordgo.User{ID: reaction.UserID},
+			ChannelID: reaction.ChannelID,
+			GuildID:   reaction.GuildID,
+		}
+	}
+	if msg == nil {
+		return nil
+	}
+
+	c.init.Do(func() {
+		if c.store == nil {
+			c.store = NewMemoryStore(c.clock)
+		}
+	})
+
+	var (
+		key  = c.namespace + c.key(msg)
+		now  = c.clock.Now()
+		wait time.Duration
+	)
+
+	err := c.store.Update(key, func(b Bucket) Bucket {
+		b, wait = c.strategy.Take(b, now)
+		return b
+	})
+	if err != nil {
+		return err
+	}
+
+	if wait > 0 {
+		return &Error{Key: key, Wait: wait}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package cooldown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func testNewCtx(authorID, channelID string) context.Context {
+	return utils.WithMsg(context.Background(), &discordgo.Message{
+		Author:    &discordgo.User{ID: authorID},
+		ChannelID: channelID,
+		GuildID:   "guild_id_1",
+	})
+}
+
+// testWait returns the wait of a cooldown error, zero if err is nil, or -1 if err is not a cooldown error.
+func testWait(err error) time.Duration {
+	if err == nil {
+		return 0
+	}
+	var cdErr *Error
+	if !errors.As(err, &cdErr) {
+		return -1
+	}
+	return cdErr.Wait
+}
+
+func TestCooldown_FixedWindow(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	cd := New(PerUser, FixedWindow(2, time.Minute)).WithClock(clock)
+
+	steps := []struct {
+		advance  time.Duration
+		author   string
+		expected time.Duration
+	}{
+		{author: "a", expected: 0},
+		{advance: 10 * time.Second, author: "a", expected: 0},
+		{advance: 5 * time.Second, author: "a", expected: 45 * time.Second},
+		{author: "b", expected: 0},
+		{advance: 45 * time.Second, author: "a", expected: 0},
+		{author: "a", expected: 0},
+		{author: "a", expected: time.Minute},
+	}
+
+	for i, step := range steps {
+		clock.advance(step.advance)
+		if wait := testWait(cd.Do(testNewCtx(step.author, "channel_id_1"))); wait != step.expected {
+			t.Errorf("step %d: expected wait %v, got %v", i, step.expected, wait)
+		}
+	}
+}
+
+func TestCooldown_reaction(t *testing.T) {
+	cd := New(PerUser, FixedWindow(1, time.Minute)).WithClock(&testClock{now: time.Unix(1600000000, 0)})
+
+	reaction := utils.WithReaction(context.Background(),
+		&discordgo.MessageReaction{UserID: "a", ChannelID: "channel_id_1", GuildID: "guild_id_1"}, false)
+
+	if wait := testWait(cd.Do(testNewCtx("a", "channel_id_1"))); wait != 0 {
+		t.Fatalf("expected message to pass, got wait %v", wait)
+	}
+	if wait := testWait(cd.Do(reaction)); wait != time.Minute {
+		t.Errorf("expected reaction to share the bucket of its user, got wait %v", wait)
+	}
+}
+
+func TestCooldown_TokenBucket(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	store := NewMemoryStore(clock)
+	cd := New(PerChannel, TokenBucket(2, 10*time.Second)).WithClock(clock).WithStore(store).Namespace("purge:")
+
+	steps := []struct {
+		advance  time.Duration
+		expected time.Duration
+	}{
+		{expected: 0},
+		{expected: 0},
+		{expected: 10 * time.Second},
+		{advance: 4 * time.Second, expected: 6 * time.Second},
+		{advance: 6 * time.Second, expected: 0},
+		{advance: 25 * time.Second, expected: 0},
+		{expected: 0},
+		{expected: 10 * time.Second},
+	}
+
+	for i, step := range steps {
+		clock.advance(step.advance)
+		if wait := testWait(cd.Do(testNewCtx("a", "channel_id_1"))); wait != step.expected {
+			t.Errorf("step %d: expected wait %v, got %v", i, step.expected, wait)
+		}
+	}
+
+	clock.advance(time.Hour)
+	_ = cd.Do(testNewCtx("a", "channel_id_2"))
+	if store.Len() != 1 {
+		t.Errorf("expected expired buckets to be discarded, got %d buckets", store.Len())
+	}
+}
+
+func TestError_Remaining(t *testing.T) {
+	err := &Error{Wait: 11200 * time.Millisecond}
+	if err.Remaining() != 12*time.Second || err.Error() != "on cooldown, try again in 12s" {
+		t.Errorf("expected wait to round up to 12s, got %v", err.Error())
+	}
+}
+
+func TestPerGuild(t *testing.T) {
+	cd := New(PerGuild, FixedWindow(1, time.Minute)).WithClock(&testClock{now: time.Unix(1600000000, 0)})
+	dm := func(channelID string) context.Context {
+		return utils.WithMsg(context.Background(), &discordgo.Message{
+			Author:    &discordgo.User{ID: "author_id_1"},
+			ChannelID: channelID,
+		})
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		cooldown bool
+	}{
+		{name: "guild", ctx: testNewCtx("author_id_1", "channel_id_1")},
+		{name: "same guild", ctx: testNewCtx("author_id_2", "channel_id_2"), cooldown: true},
+		{name: "dm", ctx: dm("dm_channel_id_1")},
+		{name: "other dm", ctx: dm("dm_channel_id_2")},
+		{name: "same dm", ctx: dm("dm_channel_id_1"), cooldown: true},
+	}
+
+	for _, tc := range testCases {
+		if wait := testWait(cd.Do(tc.ctx)); (wait > 0) != tc.cooldown {
+			t.Errorf("%s: expected cooldown %v, got wait %v", tc.name, tc.cooldown, wait)
+		}
+	}
+}
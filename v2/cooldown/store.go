@@ -0,0 +1,59 @@
+package cooldown
+
+import (
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Store keeps the Bucket of each cooldown key.
+type Store interface {
+	// Update passes the Bucket stored at key to fn and stores the Bucket it returns.
+	// If no Bucket is stored, fn receives a zero Bucket.
+	//
+	// Update must be atomic for a given key.
+	Update(key string, fn func(Bucket) Bucket) error
+}
+
+// MemoryStore is a Store that keeps buckets in memory. Expired buckets are periodically discarded.
+type MemoryStore struct {
+	mu        sync.Mutex
+	clock     Clock
+	buckets   map[string]Bucket
+	nextSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore, using clock to determine when buckets expire.
+func NewMemoryStore(clock Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:   clock,
+		buckets: map[string]Bucket{},
+	}
+}
+
+// Update atomically updates the bucket at key.
+func (s *MemoryStore) Update(key string, fn func(Bucket) Bucket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if now.After(s.nextSweep) {
+		for k, b := range s.buckets {
+			if now.After(b.Expires) {
+				delete(s.buckets, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+
+	s.buckets[key] = fn(s.buckets[key])
+	return nil
+}
+
+// Len returns the number of buckets in the store, including expired buckets that have not been discarded yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
@@ -0,0 +1,75 @@
+package cooldown
+
+import "time"
+
+type (
+	// Bucket is the state of a single cooldown key.
+	Bucket struct {
+		Count   int       // uses in the current window
+		Tokens  float64   // tokens left as of Start
+		Start   time.Time // start of the current window, or the last token refill
+		Expires time.Time // time after which the bucket is equivalent to a zero Bucket and can be discarded
+	}
+
+	// Strategy decides if a bucket can be used.
+	Strategy interface {
+		// Take uses the bucket at now, returning the updated bucket.
+		// If the bucket is exhausted, it is left unused and the wait until it can be used is returned.
+		Take(b Bucket, now time.Time) (Bucket, time.Duration)
+	}
+
+	fixedWindow struct {
+		limit  int
+		window time.Duration
+	}
+
+	tokenBucket struct {
+		burst    float64
+		interval time.Duration
+	}
+)
+
+// FixedWindow allows limit uses per window. A window starts on the first use after the previous window ended.
+func FixedWindow(limit int, window time.Duration) Strategy {
+	return &fixedWindow{limit: limit, window: window}
+}
+
+// TokenBucket allows bursts of up to burst uses, and regains a use every interval.
+func TokenBucket(burst int, interval time.Duration) Strategy {
+	return &tokenBucket{burst: float64(burst), interval: interval}
+}
+
+func (s *fixedWindow) Take(b Bucket, now time.Time) (Bucket, time.Duration) {
+	if b.Start.IsZero() || !now.Before(b.Start.Add(s.window)) {
+		b = Bucket{Start: now}
+	}
+	if b.Count >= s.limit {
+		return b, b.Start.Add(s.window).Sub(now)
+	}
+
+	b.Count++
+	b.Expires = b.Start.Add(s.window)
+	return b, 0
+}
+
+func (s *tokenBucket) Take(b Bucket, now time.Time) (Bucket, time.Duration) {
+	if b.Start.IsZero() {
+		b = Bucket{Tokens: s.burst, Start: now}
+	}
+
+	if elapsed := now.Sub(b.Start); elapsed > 0 {
+		b.Tokens += float64(elapsed) / float64(s.interval)
+		if b.Tokens > s.burst {
+			b.Tokens = s.burst
+		}
+		b.Start = now
+	}
+
+	if b.Tokens < 1 {
+		return b, time.Duration((1 - b.Tokens) * float64(s.interval))
+	}
+
+	b.Tokens--
+	b.Expires = now.Add(time.Duration((s.burst - b.Tokens) * float64(s.interval)))
+	return b, 0
+}
@@ -0,0 +1,146 @@
+package v2
+
+import (
+	"context"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// HandleEdits lets the route handle edited messages in addition to new messages, such as a command
+// corrected from "!bna" to "!ban". Messages sent more than maxAge before they were edited are ignored;
+// a maxAge of zero handles edits of messages of any age.
+//
+// An edit only invokes the route if the message did not invoke it before the edit, or failed to parse.
+// The message before the edit is only known if Session.State tracks messages (State.MaxMessageCount is non-zero);
+// otherwise every edit invokes the route.
+//
+// The setting is inherited by subroutes, and the deepest route declaring it takes priority over the Router.
+// utils.IsEdit and utils.GetBeforeEdit tell handlers if the message was edited.
+//
+// If HandleEdits is called multiple times, the previous HandleEdits call will be overwritten.
+func (r *Route) HandleEdits(maxAge time.Duration) *Route {
+	r.edits = &maxAge
+	return r
+}
+
+// HandleEdits lets every route bound to the Router handle edited messages in addition to new messages,
+// unless a route declares its own window with Route.HandleEdits. Messages sent more than maxAge before
+// they were edited are ignored; a maxAge of zero handles edits of messages of any age.
+//
+// Edits are dispatched through the same pipeline as new messages, but only to routes the message did not invoke
+// before the edit. The message before the edit is only known if Session.State tracks messages
+// (State.MaxMessageCount is non-zero); edits of messages that are not tracked are dispatched to every route handling
+// edits, and utils.GetBeforeEdit returns nil.
+func (r *Router) HandleEdits(maxAge time.Duration) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.edits = &maxAge
+	r.idx = nil
+
+	r.ensureRegistered()
+	return r
+}
+
+// onMessageUpdate is the DiscordGo MessageUpdate handler shared by all routes bound to the Router.
+func (r *Router) onMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if !contentEdited(m) || !r.index().editable {
+		return
+	}
+	ctx := utils.WithEdit(withRouter(utils.WithSes(utils.WithMsg(r.baseContext(), m.Message), s), r), m.BeforeUpdate)
+
+	r.dispatch(ctx)
+}
+
+// contentEdited reports if a MessageUpdate is an edit of the content of a message by its author.
+// Updates that only embed links have no author or edit timestamp.
+// If the message before the edit is unknown, the content is assumed to have changed.
+func contentEdited(m *discordgo.MessageUpdate) bool {
+	if m.Message == nil || m.Author == nil || m.EditedTimestamp == "" {
+		return false
+	}
+	return m.BeforeUpdate == nil || m.BeforeUpdate.Content != m.Content
+}
+
+// handlesEdits reports if route or any of its subroutes handles edited messages.
+func handlesEdits(route *Route) bool {
+	if route.edits != nil {
+		return true
+	}
+	for _, sr := range route.subroutes {
+		if handlesEdits(sr) {
+			return true
+		}
+	}
+	return false
+}
+
+// editWindow returns the maximum age of an edited message the last route in path handles.
+// The deepest route declaring a window takes priority over def, the window of the Router, which may be nil.
+//
+// Returns false if the route does not handle edited messages.
+func editWindow(path []*Route, def *time.Duration) (time.Duration, bool) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].edits != nil {
+			return *path[i].edits, true
+		}
+	}
+	if def == nil {
+		return 0, false
+	}
+	return *def, true
+}
+
+// acceptsEdit reports if the route of an invocation handles the edited message msg at time now.
+func (idx *routeIndex) acceptsEdit(inv *invocation, msg *discordgo.Message, now time.Time) bool {
+	maxAge, ok := editWindow(inv.path, idx.edits)
+	if !ok {
+		return false
+	}
+	if maxAge == 0 {
+		return true
+	}
+	sent, err := msg.Timestamp.Parse()
+	if err != nil {
+		if sent, err = discordgo.SnowflakeTimestamp(msg.ID); err != nil {
+			return false // age is unknown
+		}
+	}
+	return now.Sub(sent) <= maxAge
+}
+
+// editInvocations returns the invocations whose routes handle the edited message in ctx.
+//
+// Routes the message already invoked before the edit are skipped, so correcting the reason of "!ban @x" does not
+// ban twice. If the message before the edit is unknown, no routes are skipped.
+func (idx *routeIndex) editInvocations(ctx context.Context, invs []*invocation) []*invocation {
+	var ran []*invocation
+	if before := utils.GetBeforeEdit(ctx); before != nil {
+		ran = idx.invocations(utils.WithMsg(ctx, before))
+	}
+
+	var (
+		msg      = utils.GetMsg(ctx)
+		now      = time.Now()
+		accepted = invs[:0]
+	)
+	for _, inv := range invs {
+		if idx.acceptsEdit(inv, msg, now) && !invoked(ran, inv) {
+			accepted = append(accepted, inv)
+		}
+	}
+	return accepted
+}
+
+// invoked reports if the route of inv was successfully parsed and matched by any of invs.
+func invoked(invs []*invocation, inv *invocation) bool {
+	for _, candidate := range invs {
+		if candidate.err == nil && candidate.root == inv.root && candidate.route == inv.route {
+			return true
+		}
+	}
+	return false
+}
@@ -12,27 +12,6 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-// trimmer removes the prefix from a message and removes quotes if present
-func trimmer(message, prefix string, alias []string) string {
-
-	message = strings.Replace(message, prefix, "", 1)
-	for _, a := range alias {
-		message = strings.Replace(message, a, "", 1)
-	}
-
-	s := strings.TrimSpace(message)
-
-	if len(s) >= 2 &&
-		strings.HasPrefix(s, "\"") &&
-		strings.HasSuffix(s, "\"") {
-
-		return strings.TrimPrefix(strings.TrimSuffix(s, "\""), "\"")
-	}
-
-	return s
-
-}
-
 // Echo defines a simple EchoCmd.
 type Echo struct{}
 
@@ -46,7 +25,7 @@ func (*Echo) Handle(ctx context.Context) error {
 	}
 
 	_, _ = cmd.Ses.ChannelMessageSend(
-		cmd.Msg.ChannelID, "Echoing! "+trimmer(cmd.Msg.Content, cmd.Prefix, cmd.Alias))
+		cmd.Msg.ChannelID, "Echoing! "+strings.Join(cmd.Args, " "))
 
 	return nil
 }
@@ -75,7 +54,7 @@ func (*EchoFmt) Handle(ctx context.Context) error {
 	_, _ = cmd.Ses.ChannelMessageSendEmbed(
 		cmd.Msg.ChannelID, &discordgo.MessageEmbed{
 			Description: fmt.Sprintf(`"%s" - %s#%s`,
-				trimmer(cmd.Msg.Content, cmd.Prefix, cmd.Alias),
+				strings.Join(cmd.Args, " "),
 				cmd.Msg.Author.Username,
 				cmd.Msg.Author.Discriminator,
 			),
@@ -113,7 +92,7 @@ func (*EchoColor) Handle(ctx context.Context) error {
 %s 
 - %s#%s`,
 		codeBlockWrap,
-		trimmer(cmd.Msg.Content, cmd.Prefix, cmd.Alias),
+		strings.Join(cmd.Args, " "),
 		codeBlockWrap,
 		cmd.Msg.Author.Username,
 		cmd.Msg.Author.Discriminator,
@@ -1,27 +1,32 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	sayori "github.com/pixeltopic/sayori/v2"
+	"github.com/pixeltopic/sayori/v2/prefix"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 // Variables used for command line parameters
 var (
-	Token string
+	Token       string
+	PrefixStore string
 )
 
 const defaultPrefix = "e!"
 
 func init() {
 	flag.StringVar(&Token, "t", "", "Bot Token")
+	flag.StringVar(&PrefixStore, "p", "prefixes.json", "File storing custom guild prefixes")
 	flag.Parse()
 }
 
@@ -29,15 +34,6 @@ func onDelete(_ *discordgo.Session, d *discordgo.MessageDelete) {
 	log.Printf("A message was deleted: %v, %v, %v", d.Message.ID, d.Message.ChannelID, d.Message.GuildID)
 }
 
-// Prefix loads a prefix
-type Prefix struct{}
-
-// Load returns the Default prefix no matter what the guildID given is.
-func (p *Prefix) Load(_ string) (string, bool) { return p.Default(), true }
-
-// Default returns the default router prefix
-func (*Prefix) Default() string { return defaultPrefix }
-
 func main() {
 	dg, err := discordgo.New("Bot " + Token)
 	if err != nil {
@@ -45,17 +41,28 @@ func main() {
 		return
 	}
 
-	p := &Prefix{}
+	store, err := prefix.NewFileStore(PrefixStore)
+	if err != nil {
+		fmt.Println("error loading prefixes,", err)
+		return
+	}
+	p := prefix.New(prefix.NewCache(store, 10*time.Minute), defaultPrefix)
 
-	router := sayori.New(dg)
+	router := sayori.New(dg).ParseWith(sayori.ShellParser{})
 
-	echoColor := sayori.NewRoute(p).Do(&EchoColor{}).On("c", "color")
-	echoFmt := sayori.NewRoute(p).Do(&EchoFmt{}).On("f", "fmt").Has(echoColor)
-	echo := sayori.NewRoute(p).Do(&Echo{}).On("echo", "e").Has(echoFmt, echoColor)
+	echoColor := sayori.NewRoute(p).Do(&EchoColor{}).On("c", "color").
+		Meta(sayori.Meta{Description: "echoes text in a code block", Usage: "<text>"})
+	echoFmt := sayori.NewRoute(p).Do(&EchoFmt{}).On("f", "fmt").Has(echoColor).
+		Meta(sayori.Meta{Description: "echoes text in an embed", Usage: "<text>"})
+	echo := sayori.NewRoute(p).Do(&Echo{}).On("echo", "e").Has(echoFmt, echoColor).
+		Meta(sayori.Meta{Description: "echoes text", Usage: "<text>", Examples: []string{`echo "hello world"`}})
 
 	router.Has(echo)
 	router.Has(echoFmt)
 	router.Has(echoColor)
+	router.Has(sayori.NewRoute(p).On("help").Do(sayori.NewHelp(router, sayori.EmbedHelp{})).
+		Meta(sayori.Meta{Description: "lists commands", Usage: "[command]"}))
+	router.Has(p.Route())
 
 	router.Has(sayori.NewSubroute().Do(&OnMsg{}))
 	router.HasDefault(onDelete)
@@ -71,5 +78,8 @@ func main() {
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
 	<-sc
 
-	_ = router.Close()
+	// wait up to 10 seconds for running commands to finish
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = router.Shutdown(ctx)
 }
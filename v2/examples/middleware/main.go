@@ -1,12 +1,13 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/pixeltopic/sayori/v2/filter"
 
@@ -37,40 +38,14 @@ func (p *Prefix) Load(_ string) (string, bool) { return p.Default(), true }
 // Default returns the default router prefix
 func (*Prefix) Default() string { return defaultPrefix }
 
-// Filter will filter out invocations from a private DM channel
-type Filter struct{}
-
-// Do a check for valid invocation context
-func (*Filter) Do(ctx context.Context) error {
-	f := filter.New(filter.MsgIsPrivate)
-
-	valid, _ := f.Validate(ctx)
-	if !valid {
-		return errors.New("invalid channel :(")
-	}
-
-	return nil
-}
-
-// Validate validates if a user has admin privilege
-type Validate struct{}
-
-// Do a check for valid permissions
-func (*Validate) Do(ctx context.Context) error {
-
-	cmd := sayori.CmdFromContext(ctx)
-
-	aPerm, err := cmd.Ses.State.UserChannelPermissions(
-		cmd.Msg.Author.ID, cmd.Msg.ChannelID)
-	if err != nil {
+// Timer logs how long it took to run the middlewares and handler it wraps.
+func Timer(next sayori.HandleFunc) sayori.HandleFunc {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		err := next(ctx)
+		log.Printf("command took %v, err: %v", time.Since(start), err)
 		return err
 	}
-
-	if aPerm&discordgo.PermissionAdministrator != discordgo.PermissionAdministrator {
-		return errors.New("you don't have admin perms :(")
-	}
-
-	return nil
 }
 
 // Privilege is a privileged command only admins can use.
@@ -106,7 +81,9 @@ func main() {
 		sayori.NewRoute(&Prefix{}).
 			On("p", "priv", "privileged").
 			Do(&Privilege{}).
-			Use(&Filter{}, &Validate{}),
+			Require(sayori.Permissions{User: discordgo.PermissionAdministrator}).
+			Filter(filter.New(filter.MsgIsPrivate), sayori.FilterResolve).
+			Wrap(sayori.WrapperFunc(Timer)),
 	)
 
 	err = router.Open()
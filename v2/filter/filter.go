@@ -1,8 +1,6 @@
 package filter
 
 import (
-	"fmt"
-
 	"github.com/pixeltopic/sayori/v2/utils"
 
 	"context"
@@ -10,9 +8,10 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-// FmtErrDefault is the default format function to convert failing Filter(s) into an error string
+// FmtErrDefault is the default format function to convert failing Filter(s) into an error string.
+// It formats f with DefaultMessages.
 func FmtErrDefault(f Filter) string {
-	return fmt.Sprintf("filter fail code '%d'", f)
+	return DefaultMessages.Format(f)
 }
 
 // Filter represents a condition that prevents a `Command` or `Event` from firing.
@@ -21,9 +20,15 @@ type Filter int
 
 // AsError returns the filter as an error
 func (f Filter) AsError() error {
+	return f.AsErrorWith(FmtErrDefault)
+}
+
+// AsErrorWith returns the filter as an error, using format to convert it into an error string.
+// For example, pass the Format method of custom or localized Messages.
+func (f Filter) AsErrorWith(format func(Filter) string) error {
 	return &Error{
 		f:      f,
-		reason: FmtErrDefault(f),
+		reason: format(f),
 	}
 }
 
@@ -38,6 +43,11 @@ func (e *Error) Filter() Filter {
 	return e.f
 }
 
+// Failed returns each violated flag, in order of bit offset.
+func (e *Error) Failed() []Filter {
+	return e.f.Flags()
+}
+
 func (e *Error) Error() string {
 	return e.reason
 }
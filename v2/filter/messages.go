@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// flagNames names each Filter flag, in order of bit offset.
+//
+// The flag names, Messages and their methods are mirrored by the v1 Filter (filter.go at the repository root);
+// the modules are versioned separately, so changes to one should be carried over to the other.
+var flagNames = []struct {
+	f    Filter
+	name string
+}{
+	{MsgFromSelf, "MsgFromSelf"},
+	{MsgFromBot, "MsgFromBot"},
+	{MsgFromWebhook, "MsgFromWebhook"},
+	{MsgNoContent, "MsgNoContent"},
+	{MsgIsPrivate, "MsgIsPrivate"},
+	{MsgIsGuildText, "MsgIsGuildText"},
+}
+
+// Messages maps single Filter flags to human-readable messages explaining why a message was filtered.
+//
+// The zero Filter may be mapped to the message used when the message could not be inspected at all,
+// such as when the author of the message is unknown.
+type Messages map[Filter]string
+
+// DefaultMessages are the English Messages used by FmtErrDefault.
+var DefaultMessages = Messages{
+	0:              "message could not be inspected",
+	MsgFromSelf:    "message was sent by the bot itself",
+	MsgFromBot:     "message was sent by a bot",
+	MsgFromWebhook: "message was sent by a webhook",
+	MsgNoContent:   "message has no content",
+	MsgIsPrivate:   "message was sent in a direct message",
+	MsgIsGuildText: "message was sent in a server text channel",
+}
+
+// Catalog maps a locale, such as "en-US" or "fr", to the Messages of its language.
+type Catalog map[string]Messages
+
+// Messages returns the Messages of locale. If there are none, it falls back to the base language of locale
+// (such as "fr" for "fr-CA"), and then to DefaultMessages.
+func (c Catalog) Messages(locale string) Messages {
+	if m, ok := c[locale]; ok {
+		return m
+	}
+	if i := strings.IndexAny(locale, "-_"); i != -1 {
+		if m, ok := c[locale[:i]]; ok {
+			return m
+		}
+	}
+	return DefaultMessages
+}
+
+// Format joins the message of each flag of f with "; ".
+// Flags with no message fall back to DefaultMessages, and then to the name of the flag.
+//
+// The method value can be passed to Filter.AsErrorWith.
+func (m Messages) Format(f Filter) string {
+	flags := f.Flags()
+	if len(flags) == 0 {
+		flags = []Filter{0}
+	}
+
+	msgs := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		msg, ok := m[flag]
+		if !ok {
+			if msg, ok = DefaultMessages[flag]; !ok {
+				msg = flag.String()
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Flags decomposes f into single flags, in order of bit offset.
+func (f Filter) Flags() []Filter {
+	var flags []Filter
+	for i := 0; i < strconv.IntSize; i++ {
+		if bit := Filter(1) << i; f&bit != 0 {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}
+
+// String returns the names of the flags of f joined by "|", such as "MsgFromBot|MsgIsPrivate".
+// Unknown flags are written in hexadecimal, and the zero Filter is "0".
+func (f Filter) String() string {
+	flags := f.Flags()
+	if len(flags) == 0 {
+		return "0"
+	}
+
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, flagName(flag))
+	}
+	return strings.Join(names, "|")
+}
+
+// flagName returns the name of a single flag.
+func flagName(flag Filter) string {
+	for _, n := range flagNames {
+		if n.f == flag {
+			return n.name
+		}
+	}
+	return fmt.Sprintf("0x%x", int(flag))
+}
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFilter_String(t *testing.T) {
+	tests := []struct {
+		f        Filter
+		expected string
+	}{
+		{Filter(0), "0"},
+		{MsgFromBot, "MsgFromBot"},
+		{New(MsgIsPrivate, MsgFromBot), "MsgFromBot|MsgIsPrivate"},
+		{New(MsgFromSelf, Filter(1<<10)), "MsgFromSelf|0x400"},
+	}
+
+	for _, tt := range tests {
+		if s := tt.f.String(); s != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, s)
+		}
+	}
+}
+
+func TestMessages_Format(t *testing.T) {
+	f := New(MsgFromBot, MsgIsPrivate)
+
+	if s := FmtErrDefault(f); s != "message was sent by a bot; message was sent in a direct message" {
+		t.Errorf("unexpected default message: %s", s)
+	}
+
+	catalog := Catalog{
+		"fr": Messages{MsgFromBot: "le message a été envoyé par un bot"},
+	}
+	err := f.AsErrorWith(catalog.Messages("fr-CA").Format)
+	if err.Error() != "le message a été envoyé par un bot; message was sent in a direct message" {
+		t.Errorf("unexpected localized message: %s", err)
+	}
+	if m := catalog.Messages("de"); !reflect.DeepEqual(m, DefaultMessages) {
+		t.Errorf("expected unknown locale to fall back to DefaultMessages, got %v", m)
+	}
+
+	var filterErr *Error
+	if !errors.As(err, &filterErr) {
+		t.Fatalf("expected a filter Error, got %v", err)
+	}
+	if failed := filterErr.Failed(); !reflect.DeepEqual(failed, []Filter{MsgFromBot, MsgIsPrivate}) {
+		t.Errorf("expected failed flags to be listed, got %v", failed)
+	}
+}
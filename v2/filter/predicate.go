@@ -0,0 +1,342 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Predicate is a named condition on the message in a context.
+	//
+	// Predicates are composed with And, Or and Not, and can be used as a middleware with NewGuard.
+	Predicate interface {
+		// Name identifies the predicate in errors.
+		Name() string
+		// Eval returns nil if the message in ctx satisfies the predicate.
+		// Otherwise, it returns the predicate responsible for the failure, which is either itself or an operand.
+		Eval(ctx context.Context) Predicate
+	}
+
+	funcPredicate struct {
+		name string
+		fn   func(ctx context.Context) bool
+	}
+
+	andPredicate struct {
+		operands []Predicate
+	}
+
+	orPredicate struct {
+		operands []Predicate
+	}
+
+	notPredicate struct {
+		p Predicate
+	}
+
+	// Guard is a Middlewarer that returns a *PredicateError if the message does not satisfy its Predicate.
+	Guard struct {
+		p Predicate
+	}
+
+	// PredicateError is returned by Guard when a Predicate fails.
+	PredicateError struct {
+		// Predicate is the innermost predicate responsible for the failure.
+		// For example, it is the first failing operand of an And.
+		Predicate Predicate
+	}
+)
+
+// Built-in predicates matching the conditions of the Filter bitset.
+// A Filter ignores messages satisfying its conditions, so it is equivalent to the negation of these predicates.
+// See Filter.Predicate.
+var (
+	// FromSelf holds if the message was sent by the bot itself.
+	FromSelf = Func("FromSelf", func(ctx context.Context) bool {
+		msg, ses := utils.GetMsg(ctx), utils.GetSes(ctx)
+		return msg != nil && msg.Author != nil && ses != nil && ses.State != nil && ses.State.User != nil &&
+			msg.Author.ID == ses.State.User.ID
+	})
+	// FromBot holds if the message was sent by a bot.
+	FromBot = Func("FromBot", func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && msg.Author != nil && msg.Author.Bot
+	})
+	// FromWebhook holds if the message was sent by a webhook.
+	FromWebhook = Func("FromWebhook", func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && len(msg.WebhookID) != 0
+	})
+	// NoContent holds if the message has no text body, such as a message with only attachments.
+	NoContent = Func("NoContent", func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && len(msg.Content) == 0
+	})
+	// IsPrivate holds if the message was sent in a DM.
+	IsPrivate = Func("IsPrivate", func(ctx context.Context) bool {
+		msg, ses := utils.GetMsg(ctx), utils.GetSes(ctx)
+		return msg != nil && ses != nil && comesFromDM(ses, msg)
+	})
+	// IsGuildText holds if the message was sent in a guild text channel.
+	IsGuildText = Func("IsGuildText", func(ctx context.Context) bool {
+		msg, ses := utils.GetMsg(ctx), utils.GetSes(ctx)
+		return msg != nil && ses != nil && comesFromGuild(ses, msg)
+	})
+	// HasAttachments holds if the message has at least one attachment.
+	HasAttachments = Func("HasAttachments", func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && len(msg.Attachments) != 0
+	})
+	// MentionsBot holds if the message mentions the bot.
+	MentionsBot = Func("MentionsBot", func(ctx context.Context) bool {
+		msg, ses := utils.GetMsg(ctx), utils.GetSes(ctx)
+		if msg == nil || ses == nil || ses.State == nil || ses.State.User == nil {
+			return false
+		}
+		for _, u := range msg.Mentions {
+			if u.ID == ses.State.User.ID {
+				return true
+			}
+		}
+		return false
+	})
+)
+
+// Func returns a Predicate named name that holds if fn returns true.
+func Func(name string, fn func(ctx context.Context) bool) Predicate {
+	return &funcPredicate{name: name, fn: fn}
+}
+
+func (p *funcPredicate) Name() string {
+	return p.name
+}
+
+func (p *funcPredicate) Eval(ctx context.Context) Predicate {
+	if p.fn(ctx) {
+		return nil
+	}
+	return p
+}
+
+// And returns a Predicate that holds if all predicates hold. Predicates are evaluated in order,
+// and the first failure is returned. And with no predicates always holds.
+func And(predicates ...Predicate) Predicate {
+	return &andPredicate{operands: predicates}
+}
+
+func (p *andPredicate) Name() string {
+	return joinNames(p.operands, " AND ")
+}
+
+func (p *andPredicate) Eval(ctx context.Context) Predicate {
+	for _, operand := range p.operands {
+		if failed := operand.Eval(ctx); failed != nil {
+			return failed
+		}
+	}
+	return nil
+}
+
+// Or returns a Predicate that holds if any of predicates holds. Predicates are evaluated in order until one holds.
+// If none hold, the Or itself is the failure. Or with no predicates never holds.
+func Or(predicates ...Predicate) Predicate {
+	return &orPredicate{operands: predicates}
+}
+
+func (p *orPredicate) Name() string {
+	return joinNames(p.operands, " OR ")
+}
+
+func (p *orPredicate) Eval(ctx context.Context) Predicate {
+	for _, operand := range p.operands {
+		if operand.Eval(ctx) == nil {
+			return nil
+		}
+	}
+	return p
+}
+
+// Not returns a Predicate that holds if p does not hold.
+func Not(p Predicate) Predicate {
+	return &notPredicate{p: p}
+}
+
+func (p *notPredicate) Name() string {
+	return "NOT " + p.p.Name()
+}
+
+func (p *notPredicate) Eval(ctx context.Context) Predicate {
+	if p.p.Eval(ctx) == nil {
+		return p
+	}
+	return nil
+}
+
+// joinNames names a combination of predicates, such as "(FromBot OR FromWebhook)".
+func joinNames(predicates []Predicate, sep string) string {
+	names := make([]string, 0, len(predicates))
+	for _, p := range predicates {
+		names = append(names, p.Name())
+	}
+	return "(" + strings.Join(names, sep) + ")"
+}
+
+// ContentMatches returns a Predicate that holds if the content of the message matches re.
+func ContentMatches(re *regexp.Regexp) Predicate {
+	return Func(fmt.Sprintf("ContentMatches(%s)", re), func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && re.MatchString(msg.Content)
+	})
+}
+
+// AuthorIs returns a Predicate that holds if the message was sent by a user with one of ids.
+func AuthorIs(ids ...string) Predicate {
+	return Func(fmt.Sprintf("AuthorIs(%s)", strings.Join(ids, ", ")), func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		if msg == nil || msg.Author == nil {
+			return false
+		}
+		for _, id := range ids {
+			if msg.Author.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ChannelIs returns a Predicate that holds if the message was sent in a channel with one of ids.
+func ChannelIs(ids ...string) Predicate {
+	return Func(fmt.Sprintf("ChannelIs(%s)", strings.Join(ids, ", ")), func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		if msg == nil {
+			return false
+		}
+		for _, id := range ids {
+			if msg.ChannelID == id {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ChannelTypeIs returns a Predicate that holds if the message was sent in a channel of one of types.
+// The channel is read from Session.State, or requested from Discord if it is not cached.
+func ChannelTypeIs(types ...discordgo.ChannelType) Predicate {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, fmt.Sprint(int(t)))
+	}
+
+	return Func(fmt.Sprintf("ChannelTypeIs(%s)", strings.Join(names, ", ")), func(ctx context.Context) bool {
+		msg, ses := utils.GetMsg(ctx), utils.GetSes(ctx)
+		if msg == nil || ses == nil {
+			return false
+		}
+		channel, err := ses.State.Channel(msg.ChannelID)
+		if err != nil {
+			if channel, err = ses.Channel(msg.ChannelID); err != nil {
+				return false
+			}
+		}
+		for _, t := range types {
+			if channel.Type == t {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Preconditions of Filter.Validate, which rejects messages it cannot inspect.
+var (
+	hasMessage = Func("HasMessage", func(ctx context.Context) bool {
+		return utils.GetMsg(ctx) != nil && utils.GetSes(ctx) != nil
+	})
+	hasAuthor = Func("HasAuthor", func(ctx context.Context) bool {
+		msg := utils.GetMsg(ctx)
+		return msg != nil && msg.Author != nil
+	})
+	hasSelf = Func("HasSelf", func(ctx context.Context) bool {
+		ses := utils.GetSes(ctx)
+		return ses != nil && ses.State != nil && ses.State.User != nil
+	})
+)
+
+// Predicate returns the Predicate equivalent to the Filter, which holds if the message is not ignored by any of its
+// conditions. For example, New(MsgFromBot, MsgIsPrivate).Predicate() is equivalent to
+// And(Not(FromBot), Not(IsPrivate)).
+//
+// Like Validate, the Predicate does not hold if the context has no message or session,
+// if MsgFromSelf or MsgFromBot is set and the message has no author,
+// or if MsgFromSelf is set and the state of the session has no user.
+func (f Filter) Predicate() Predicate {
+	conditions := []struct {
+		f        Filter
+		p        Predicate
+		requires []Predicate
+	}{
+		{MsgFromSelf, FromSelf, []Predicate{hasAuthor, hasSelf}},
+		{MsgFromBot, FromBot, []Predicate{hasAuthor}},
+		{MsgFromWebhook, FromWebhook, nil},
+		{MsgNoContent, NoContent, nil},
+		{MsgIsPrivate, IsPrivate, nil},
+		{MsgIsGuildText, IsGuildText, nil},
+	}
+
+	var (
+		preconditions = []Predicate{hasMessage}
+		predicates    []Predicate
+	)
+	for _, c := range conditions {
+		if !f.Contains(c.f) {
+			continue
+		}
+		for _, p := range c.requires {
+			if !containsPredicate(preconditions, p) {
+				preconditions = append(preconditions, p)
+			}
+		}
+		predicates = append(predicates, Not(c.p))
+	}
+	return And(append(preconditions, predicates...)...)
+}
+
+// containsPredicate reports if p is one of predicates.
+func containsPredicate(predicates []Predicate, p Predicate) bool {
+	for _, candidate := range predicates {
+		if candidate == p {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGuard returns a Guard for p.
+func NewGuard(p Predicate) *Guard {
+	return &Guard{p: p}
+}
+
+// Do returns a *PredicateError if the message in ctx does not satisfy the Predicate of the Guard.
+func (g *Guard) Do(ctx context.Context) error {
+	if failed := g.p.Eval(ctx); failed != nil {
+		return &PredicateError{Predicate: failed}
+	}
+	return nil
+}
+
+// Name returns the name of the failed predicate.
+func (e *PredicateError) Name() string {
+	return e.Predicate.Name()
+}
+
+func (e *PredicateError) Error() string {
+	return fmt.Sprintf("filter '%s' failed", e.Name())
+}
@@ -0,0 +1,173 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPredicate_combinators(t *testing.T) {
+	var (
+		holds = Func("holds", func(_ context.Context) bool { return true })
+		fails = Func("fails", func(_ context.Context) bool { return false })
+		ctx   = context.Background()
+	)
+
+	tests := []struct {
+		p      Predicate
+		name   string
+		failed string // name of the failed predicate, empty if p holds
+	}{
+		{p: And(), name: "()"},
+		{p: And(holds, fails, Not(holds)), name: "(holds AND fails AND NOT holds)", failed: "fails"},
+		{p: And(holds, Not(fails)), name: "(holds AND NOT fails)"},
+		{p: Or(), name: "()", failed: "()"},
+		{p: Or(fails, holds), name: "(fails OR holds)"},
+		{p: Or(fails, Not(holds)), name: "(fails OR NOT holds)", failed: "(fails OR NOT holds)"},
+		{p: And(holds, Or(fails, And(holds, fails))), name: "(holds AND (fails OR (holds AND fails)))",
+			failed: "(fails OR (holds AND fails))"},
+		{p: Not(And(holds, fails)), name: "NOT (holds AND fails)"},
+	}
+
+	for _, tt := range tests {
+		if tt.p.Name() != tt.name {
+			t.Errorf("expected name %q, got %q", tt.name, tt.p.Name())
+		}
+
+		failed := tt.p.Eval(ctx)
+		switch {
+		case tt.failed == "" && failed != nil:
+			t.Errorf("%s: expected to hold, failed %s", tt.name, failed.Name())
+		case tt.failed != "" && failed == nil:
+			t.Errorf("%s: expected %s to fail", tt.name, tt.failed)
+		case tt.failed != "" && failed.Name() != tt.failed:
+			t.Errorf("%s: expected %s to fail, got %s", tt.name, tt.failed, failed.Name())
+		}
+	}
+}
+
+func TestPredicate_builtins(t *testing.T) {
+	ctx := testNewCtx("guild_id_1", "hello <@self_id_1>", "author_id_1", "self_id_1", false)
+	msg := utils.GetMsg(ctx)
+	msg.Mentions = []*discordgo.User{{ID: "self_id_1"}}
+
+	tests := []struct {
+		p     Predicate
+		holds bool
+	}{
+		{ContentMatches(regexp.MustCompile(`^hello`)), true},
+		{ContentMatches(regexp.MustCompile(`^bye`)), false},
+		{AuthorIs("author_id_2", "author_id_1"), true},
+		{AuthorIs("author_id_2"), false},
+		{ChannelIs("channel_id_1"), false},
+		{MentionsBot, true},
+		{HasAttachments, false},
+		{FromSelf, false},
+		{FromBot, false},
+		{NoContent, false},
+	}
+
+	for _, tt := range tests {
+		if holds := tt.p.Eval(ctx) == nil; holds != tt.holds {
+			t.Errorf("%s: expected %v, got %v", tt.p.Name(), tt.holds, holds)
+		}
+	}
+}
+
+func TestFilter_Predicate(t *testing.T) {
+	ctxs := []context.Context{
+		testNewCtx("guild_id_1", "content", "author_id_1", "self_id_1", false),
+		testNewCtx("guild_id_1", "", "author_id_1", "self_id_1", true),
+		testNewCtx("guild_id_1", "content", "self_id_1", "self_id_1", true),
+	}
+	filters := []Filter{
+		New(),
+		New(MsgFromBot),
+		New(MsgFromSelf, MsgNoContent),
+		New(MsgFromSelf, MsgFromBot, MsgFromWebhook, MsgNoContent),
+	}
+
+	for _, f := range filters {
+		for i, ctx := range ctxs {
+			valid, _ := f.Validate(ctx)
+			if holds := f.Predicate().Eval(ctx) == nil; holds != valid {
+				t.Errorf("filter %d, ctx %d: expected predicate to be %v, got %v", f, i, valid, holds)
+			}
+		}
+	}
+}
+
+// testOfflineTransport fails every request, so lookups of channels missing from the state fall back to the message.
+type testOfflineTransport struct{}
+
+func (testOfflineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
+
+func TestFilter_Predicate_nil(t *testing.T) {
+	session := func(state *discordgo.State) *discordgo.Session {
+		return &discordgo.Session{
+			State:       state,
+			Ratelimiter: discordgo.NewRatelimiter(),
+			Client:      &http.Client{Transport: testOfflineTransport{}},
+		}
+	}
+	stateWithUser := discordgo.NewState()
+	stateWithUser.User = &discordgo.User{ID: "self_id_1"}
+	author := &discordgo.User{ID: "author_id_1"}
+
+	ctxs := map[string]context.Context{
+		"nil author":     testNewBadCtx(session(stateWithUser), &discordgo.Message{GuildID: "guild_id_1", Content: "content"}),
+		"nil state":      testNewBadCtx(session(nil), &discordgo.Message{Author: author, Content: "content"}),
+		"nil state user": testNewBadCtx(session(discordgo.NewState()), &discordgo.Message{Author: author, GuildID: "guild_id_1"}),
+		"nil both":       testNewBadCtx(session(nil), &discordgo.Message{}),
+		"nil message":    utils.WithSes(context.Background(), session(stateWithUser)),
+		"nil session":    utils.WithMsg(context.Background(), &discordgo.Message{Author: author}),
+	}
+	filters := []Filter{
+		New(),
+		MsgFromSelf,
+		MsgFromBot,
+		MsgFromWebhook,
+		MsgNoContent,
+		MsgIsPrivate,
+		MsgIsGuildText,
+		New(MsgFromSelf, MsgFromBot, MsgFromWebhook, MsgNoContent, MsgIsPrivate, MsgIsGuildText),
+	}
+
+	for _, f := range filters {
+		for name, ctx := range ctxs {
+			valid, _ := f.Validate(ctx)
+			if holds := f.Predicate().Eval(ctx) == nil; holds != valid {
+				t.Errorf("filter %v, %s: expected predicate to be %v, got %v", f, name, valid, holds)
+			}
+		}
+	}
+}
+
+func TestGuard_Do(t *testing.T) {
+	ctx := testNewCtx("guild_id_1", "", "author_id_1", "self_id_1", true)
+
+	err := NewGuard(And(Not(FromWebhook), Not(FromBot), Not(NoContent))).Do(ctx)
+
+	var predErr *PredicateError
+	if !errors.As(err, &predErr) {
+		t.Fatalf("expected a PredicateError, got %v", err)
+	}
+	if predErr.Name() != "NOT FromBot" {
+		t.Errorf("expected failed predicate to be 'NOT FromBot', got %s", predErr.Name())
+	}
+	if err.Error() != "filter 'NOT FromBot' failed" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	if err := NewGuard(Not(FromWebhook)).Do(ctx); err != nil {
+		t.Errorf("expected guard to pass, got %v", err)
+	}
+}
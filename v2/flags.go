@@ -0,0 +1,207 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+// FlagKind determines how a Flag consumes values.
+type FlagKind int
+
+const (
+	// FlagBool takes no value, such as -q or --quiet.
+	FlagBool FlagKind = iota
+	// FlagValue takes exactly one value, such as -n 5, -n5, --count 5 or --count=5.
+	FlagValue
+	// FlagRepeated takes one value, and may be passed more than once.
+	FlagRepeated
+)
+
+var (
+	// ErrFlagUnknown is wrapped by a FlagError when a flag was not declared.
+	ErrFlagUnknown = errors.New("unknown flag")
+	// ErrFlagNoValue is wrapped by a FlagError when a flag that takes a value has none.
+	ErrFlagNoValue = errors.New("flag needs a value")
+	// ErrFlagHasValue is wrapped by a FlagError when a FlagBool is given a value.
+	ErrFlagHasValue = errors.New("flag does not take a value")
+	// ErrFlagDuplicate is wrapped by a FlagError when a flag that is not FlagRepeated is passed more than once.
+	ErrFlagDuplicate = errors.New("flag passed more than once")
+)
+
+// Flag declares an option of a Route.
+//
+// At least one of Long or Short must be set. Flags are stored under Long, or Short if there is no Long name.
+type Flag struct {
+	Long  string // used as --long
+	Short rune   // used as -s, and can be combined with other short flags such as -qv
+	Kind  FlagKind
+}
+
+// key returns the name the flag is stored under.
+func (f Flag) key() string {
+	if f.Long != "" {
+		return f.Long
+	}
+	return string(f.Short)
+}
+
+// FlagError is returned when the flags of a command are unknown or malformed.
+type FlagError struct {
+	Flag string // flag as written, such as --count or -n
+	Err  error
+}
+
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("%v: '%s'", e.Err, e.Flag)
+}
+
+// Unwrap returns the underlying error.
+func (e *FlagError) Unwrap() error {
+	return e.Err
+}
+
+// flagParser separates declared flags from positional arguments.
+type flagParser struct {
+	decl   []Flag
+	flags  utils.Flags
+	pos    []string
+	args   []string
+	cursor int
+}
+
+// parseFlags separates args into positional arguments and flags declared in decl.
+//
+// Parsing follows GNU conventions: flags and positional arguments can be interleaved, and "--" ends flag parsing.
+// A lone "-" and negative numbers are positional arguments.
+func parseFlags(decl []Flag, args []string) ([]string, utils.Flags, error) {
+	p := &flagParser{decl: decl, flags: utils.Flags{}, pos: []string{}, args: args}
+
+	for p.cursor < len(p.args) {
+		arg := p.args[p.cursor]
+		p.cursor++
+
+		var err error
+		switch {
+		case arg == "--":
+			p.pos = append(p.pos, p.args[p.cursor:]...)
+			p.cursor = len(p.args)
+		case strings.HasPrefix(arg, "--"):
+			err = p.parseLong(arg)
+		case len(arg) > 1 && arg[0] == '-' && !isNumber(arg):
+			err = p.parseShort(arg)
+		default:
+			p.pos = append(p.pos, arg)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return p.pos, p.flags, nil
+}
+
+// parseLong parses an argument of the form --name or --name=value.
+func (p *flagParser) parseLong(arg string) error {
+	name, value := arg[2:], ""
+	hasValue := false
+	if i := strings.IndexByte(name, '='); i != -1 {
+		name, value, hasValue = name[:i], name[i+1:], true
+	}
+
+	f, ok := p.find(func(f Flag) bool { return f.Long != "" && f.Long == name })
+	if !ok {
+		return &FlagError{Flag: "--" + name, Err: ErrFlagUnknown}
+	}
+
+	if f.Kind == FlagBool {
+		if hasValue {
+			return &FlagError{Flag: "--" + name, Err: ErrFlagHasValue}
+		}
+		return p.set(f, "--"+name, nil)
+	}
+
+	if !hasValue {
+		var err error
+		if value, err = p.next("--" + name); err != nil {
+			return err
+		}
+	}
+	return p.set(f, "--"+name, &value)
+}
+
+// parseShort parses an argument of the form -s, -abc, -nvalue or -n value.
+func (p *flagParser) parseShort(arg string) error {
+	shorts := []rune(arg[1:])
+
+	for i, r := range shorts {
+		written := "-" + string(r)
+		f, ok := p.find(func(f Flag) bool { return f.Short != 0 && f.Short == r })
+		if !ok {
+			return &FlagError{Flag: written, Err: ErrFlagUnknown}
+		}
+
+		if f.Kind == FlagBool {
+			if err := p.set(f, written, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// a flag taking a value consumes the rest of the argument, or the next argument
+		value := string(shorts[i+1:])
+		if value == "" {
+			var err error
+			if value, err = p.next(written); err != nil {
+				return err
+			}
+		}
+		return p.set(f, written, &value)
+	}
+
+	return nil
+}
+
+// next consumes the next argument as the value of a flag.
+func (p *flagParser) next(written string) (string, error) {
+	if p.cursor >= len(p.args) {
+		return "", &FlagError{Flag: written, Err: ErrFlagNoValue}
+	}
+	value := p.args[p.cursor]
+	p.cursor++
+	return value, nil
+}
+
+// find returns the first declared flag matching fn.
+func (p *flagParser) find(fn func(Flag) bool) (Flag, bool) {
+	for _, f := range p.decl {
+		if fn(f) {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// set records a flag. value is nil for a FlagBool.
+func (p *flagParser) set(f Flag, written string, value *string) error {
+	key := f.key()
+	values, seen := p.flags[key]
+	if seen && f.Kind != FlagRepeated {
+		return &FlagError{Flag: written, Err: ErrFlagDuplicate}
+	}
+	if value == nil {
+		p.flags[key] = []string{}
+		return nil
+	}
+	p.flags[key] = append(values, *value)
+	return nil
+}
+
+// isNumber returns true if arg is a number, such as -5 or -0.5.
+func isNumber(arg string) bool {
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
@@ -0,0 +1,136 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+func TestParseFlags(t *testing.T) {
+	decl := []Flag{
+		{Long: "user", Short: 'u', Kind: FlagRepeated},
+		{Long: "before", Short: 'b', Kind: FlagValue},
+		{Long: "quiet", Short: 'q', Kind: FlagBool},
+		{Short: 'v', Kind: FlagBool},
+	}
+
+	testCases := []struct {
+		name    string
+		args    string
+		pos     []string
+		flags   utils.Flags
+		err     error
+		errFlag string
+	}{
+		{
+			name:  "interleaved flags and positional arguments",
+			args:  "50 --user @bob --before 1h -q",
+			pos:   []string{"50"},
+			flags: utils.Flags{"user": {"@bob"}, "before": {"1h"}, "quiet": {}},
+		},
+		{
+			name:  "combined and attached short flags",
+			args:  "-qvb1h -u @a -u@b arg",
+			pos:   []string{"arg"},
+			flags: utils.Flags{"quiet": {}, "v": {}, "before": {"1h"}, "user": {"@a", "@b"}},
+		},
+		{
+			name:  "equals syntax, negative numbers, lone dash and end of flags",
+			args:  "--before=2h -5 - -- -q --user",
+			pos:   []string{"-5", "-", "-q", "--user"},
+			flags: utils.Flags{"before": {"2h"}},
+		},
+		{name: "unknown long flag", args: "--nope", err: ErrFlagUnknown, errFlag: "--nope"},
+		{name: "unknown short flag", args: "-qx", err: ErrFlagUnknown, errFlag: "-x"},
+		{name: "missing value", args: "1 --before", err: ErrFlagNoValue, errFlag: "--before"},
+		{name: "value on a boolean flag", args: "--quiet=yes", err: ErrFlagHasValue, errFlag: "--quiet"},
+		{name: "duplicate flag", args: "-b 1h --before 2h", err: ErrFlagDuplicate, errFlag: "--before"},
+	}
+
+	for _, c := range testCases {
+		pos, flags, err := parseFlags(decl, strings.Fields(c.args))
+
+		if c.err == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+				continue
+			}
+			if !strSliceEqual(c.pos, pos, false) {
+				t.Errorf("%s: expected positional args %q, got %q", c.name, c.pos, pos)
+			}
+			if !reflect.DeepEqual(c.flags, flags) {
+				t.Errorf("%s: expected flags %v, got %v", c.name, c.flags, flags)
+			}
+			continue
+		}
+
+		var flagErr *FlagError
+		if !errors.As(err, &flagErr) || !errors.Is(err, c.err) || flagErr.Flag != c.errFlag {
+			t.Errorf("%s: expected FlagError wrapping %v for %s, got %v", c.name, c.err, c.errFlag, err)
+		}
+	}
+}
+
+func TestRoute_Flags(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		cmd    *CmdContext
+	)
+
+	router.Has(NewRoute(&testPref{}).On("purge").
+		Flags(Flag{Long: "user", Short: 'u', Kind: FlagValue}, Flag{Short: 'q'}).
+		Args(Arg{Name: "count", Type: ArgTypeInt}).
+		Do(&testCmd{
+			HandleCallback: func(ctx context.Context) error {
+				cmd = CmdFromContext(ctx)
+				return nil
+			},
+		}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"purge -q 50 --user <@123>"))
+	if cmd == nil {
+		t.Fatal("expected handler to run")
+	}
+	if !strSliceEqual([]string{"50"}, cmd.Args, false) || cmd.IntArg("count") != 50 {
+		t.Errorf("expected flags to be removed from args, got %v", cmd.Args)
+	}
+	if !cmd.Flags.Has("q") || cmd.Flags.Value("user") != "<@123>" {
+		t.Errorf("unexpected flags %v", cmd.Flags)
+	}
+}
+
+func TestRoute_FlagsMiddlewares(t *testing.T) {
+	var (
+		ses      = makeMockSes()
+		router   = New(ses)
+		trace    []string
+		resolved error
+		blocked  = &testMiddleware{name: "blacklist", trace: &trace}
+	)
+
+	router.Use(blocked)
+	router.Has(NewRoute(&testPref{}).On("purge").
+		Flags(Flag{Long: "user", Kind: FlagValue}).
+		Do(&testCmd{
+			ResolveCallback: func(ctx context.Context) {
+				resolved = utils.GetErr(ctx)
+			},
+		}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"purge --unknown"))
+	var flagErr *FlagError
+	if !errors.As(resolved, &flagErr) || !strSliceEqual([]string{"blacklist"}, trace, false) {
+		t.Errorf("expected FlagError to be resolved after middlewares %v ran, got %v", trace, resolved)
+	}
+
+	blocked.err = errors.New("denied")
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"purge --unknown"))
+	if resolved != blocked.err {
+		t.Errorf("expected middleware error to be resolved before flags are parsed, got %v", resolved)
+	}
+}
@@ -0,0 +1,445 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Limits of Discord messages, in characters.
+const (
+	maxContentLength          = 2000
+	maxEmbedLength            = 6000
+	maxEmbedDescriptionLength = 2048
+	maxEmbedFields            = 25
+	maxEmbedFieldValueLength  = 1024
+)
+
+type (
+	// Meta describes a Route for the help command.
+	Meta struct {
+		Description string
+		// Usage shows the syntax of the arguments of a route, such as "<count> [reason]".
+		// If empty, it will be generated from the Args and Flags declared on the route.
+		Usage string
+		// Examples of invoking the route, without the prefix.
+		Examples []string
+		// Category groups root routes in the command list.
+		Category string
+		// Hidden routes and their subroutes are excluded from help.
+		Hidden bool
+	}
+
+	// HelpEntry describes a route to be rendered by a HelpFormatter.
+	HelpEntry struct {
+		Prefix      string   // prefix of the root route in the current guild
+		Path        []string // first alias of each route from the root route to this route
+		Aliases     []string
+		Usage       string
+		Meta        Meta
+		Subcommands []HelpEntry // visible immediate subroutes
+	}
+
+	// HelpFormatter renders help into a message.
+	HelpFormatter interface {
+		// FormatList renders all visible root routes into pages that each fit in a message.
+		// Multiple pages are sent with a Paginator.
+		FormatList(entries []HelpEntry) []Page
+		// FormatDetail renders a single route.
+		FormatDetail(entry HelpEntry) *discordgo.MessageSend
+	}
+
+	// TextHelp is a HelpFormatter rendering help as plain text.
+	TextHelp struct{}
+
+	// EmbedHelp is a HelpFormatter rendering help as an embed.
+	EmbedHelp struct {
+		Color int
+	}
+
+	// HelpNotFoundError is returned by Help when the requested command does not exist or is hidden.
+	HelpNotFoundError struct {
+		Command string
+	}
+
+	// Help is a Handler that renders help for the routes bound to a Router.
+	//
+	// Invoked with no arguments, it lists all root routes. Otherwise, the arguments are treated as a path of
+	// aliases (such as "echo fmt") and the matching route is described along with its subroutes.
+	Help struct {
+		router *Router
+		f      HelpFormatter
+	}
+)
+
+func (e *HelpNotFoundError) Error() string {
+	return fmt.Sprintf("no command named '%s'", e.Command)
+}
+
+// NewHelp returns a help Handler for the routes bound to router. If f is nil, it defaults to TextHelp.
+func NewHelp(router *Router, f HelpFormatter) *Help {
+	if f == nil {
+		f = TextHelp{}
+	}
+	return &Help{router: router, f: f}
+}
+
+// Handle sends help for the requested command. A list of commands spanning multiple pages is paginated,
+// unless the handler blocks the session from receiving events, in which case each page is sent as a message.
+func (h *Help) Handle(ctx context.Context) error {
+	cmd := CmdFromContext(ctx)
+
+	pages, err := h.render(cmd.Msg.GuildID, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(pages) > 1 && !eventsBlocked(ctx) {
+		return NewPaginator(pages...).Run(ctx)
+	}
+
+	for _, page := range pages {
+		if _, err = cmd.Ses.ChannelMessageSendComplex(cmd.Msg.ChannelID, &discordgo.MessageSend{
+			Content: page.Content,
+			Embed:   page.Embed,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render formats help for a path of aliases, or the list of all commands if path is empty.
+func (h *Help) render(guildID string, path []string) ([]Page, error) {
+	roots := h.router.rootRoutes()
+
+	if len(path) == 0 {
+		entries := make([]HelpEntry, 0, len(roots))
+		for _, root := range roots {
+			if visibleInHelp(root) {
+				entries = append(entries, newHelpEntry(root, root.getGuildPrefix(guildID), nil))
+			}
+		}
+		return h.f.FormatList(entries), nil
+	}
+
+	entry, ok := findHelpEntry(roots, path, guildID)
+	if !ok {
+		return nil, &HelpNotFoundError{Command: strings.Join(path, " ")}
+	}
+	msg := h.f.FormatDetail(entry)
+	return []Page{{Content: msg.Content, Embed: msg.Embed}}, nil
+}
+
+// Resolve sends any error to the channel.
+func (h *Help) Resolve(ctx context.Context) {
+	cmd := CmdFromContext(ctx)
+
+	if cmd.Err != nil {
+		_, _ = cmd.Ses.ChannelMessageSend(cmd.Msg.ChannelID, cmd.Err.Error())
+	}
+}
+
+// rootRoutes returns the root routes bound to the Router in registration order.
+func (r *Router) rootRoutes() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]*Route, 0, len(r.roots))
+	for _, root := range r.roots {
+		routes = append(routes, root.route)
+	}
+	return routes
+}
+
+// visibleInHelp returns true if the route can be invoked and is not hidden.
+func visibleInHelp(r *Route) bool {
+	return !r.IsDefault() && r.h != nil && !r.meta.Hidden
+}
+
+// findHelpEntry finds the route matching a path of aliases.
+// Like routing, the most recently added route wins if aliases are duplicated.
+func findHelpEntry(roots []*Route, path []string, guildID string) (HelpEntry, bool) {
+	var root *Route
+	for _, r := range roots {
+		if visibleInHelp(r) && r.HasAlias(path[0]) {
+			root = r
+		}
+	}
+	if root == nil {
+		return HelpEntry{}, false
+	}
+
+	var (
+		route   = root
+		aliases = []string{root.aliases[0]}
+	)
+	for _, alias := range path[1:] {
+		var next *Route
+		for _, sr := range route.findAllSubroutes(alias) {
+			if visibleInHelp(sr) {
+				next = sr
+			}
+		}
+		if next == nil {
+			return HelpEntry{}, false
+		}
+		route = next
+		aliases = append(aliases, route.aliases[0])
+	}
+
+	return newHelpEntry(route, root.getGuildPrefix(guildID), aliases[:len(aliases)-1]), true
+}
+
+// newHelpEntry describes a route. parents are the aliases leading to the route.
+func newHelpEntry(r *Route, prefix string, parents []string) HelpEntry {
+	path := append(append([]string{}, parents...), r.aliases[0])
+
+	entry := HelpEntry{
+		Prefix:  prefix,
+		Path:    path,
+		Aliases: append([]string{}, r.aliases...),
+		Usage:   r.meta.Usage,
+		Meta:    r.meta,
+	}
+	if entry.Usage == "" {
+		entry.Usage = generateUsage(r)
+	}
+
+	for _, sr := range r.subroutes {
+		if visibleInHelp(sr) {
+			entry.Subcommands = append(entry.Subcommands, newHelpEntry(sr, prefix, path))
+		}
+	}
+
+	return entry
+}
+
+// generateUsage describes the declared flags and arguments of a route, such as "[-q] [--user value] <count> [reason...]".
+func generateUsage(r *Route) string {
+	var usage []string
+
+	for _, f := range r.flags {
+		name := "--" + f.Long
+		if f.Long == "" {
+			name = "-" + string(f.Short)
+		}
+		if f.Kind != FlagBool {
+			name += " value"
+		}
+		usage = append(usage, "["+name+"]")
+	}
+
+	for _, a := range r.args {
+		name := a.Name
+		if a.Variadic {
+			name += "..."
+		}
+		if a.Optional {
+			usage = append(usage, "["+name+"]")
+		} else {
+			usage = append(usage, "<"+name+">")
+		}
+	}
+
+	return strings.Join(usage, " ")
+}
+
+// Invocation returns the command without arguments, such as "!echo fmt".
+func (e HelpEntry) Invocation() string {
+	return e.Prefix + strings.Join(e.Path, " ")
+}
+
+// groupByCategory groups entries by category, sorted by category name.
+// Entries without a category come first, and entries keep their order within a category.
+func groupByCategory(entries []HelpEntry) (categories []string, grouped map[string][]HelpEntry) {
+	grouped = map[string][]HelpEntry{}
+	for _, e := range entries {
+		if _, ok := grouped[e.Meta.Category]; !ok {
+			categories = append(categories, e.Meta.Category)
+		}
+		grouped[e.Meta.Category] = append(grouped[e.Meta.Category], e)
+	}
+	sort.Strings(categories)
+	return categories, grouped
+}
+
+// listLine describes a root route in the command list.
+func listLine(e HelpEntry) string {
+	line := fmt.Sprintf("`%s`", e.Invocation())
+	if e.Meta.Description != "" {
+		line += " - " + e.Meta.Description
+	}
+	return line
+}
+
+// truncate shortens s to at most limit characters, ending with an ellipsis if it was shortened.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
+
+// packLines joins lines with newlines into chunks of at most limit characters.
+// Lines are never split across chunks; lines longer than limit are truncated.
+func packLines(lines []string, limit int) []string {
+	var (
+		chunks []string
+		chunk  []string
+		size   int
+	)
+
+	for _, line := range lines {
+		line = truncate(line, limit)
+		n := utf8.RuneCountInString(line)
+		if len(chunk) != 0 && size+1+n > limit {
+			chunks = append(chunks, strings.Join(chunk, "\n"))
+			chunk, size = nil, 0
+		}
+		if len(chunk) != 0 {
+			size++
+		}
+		chunk = append(chunk, line)
+		size += n
+	}
+	if len(chunk) != 0 {
+		chunks = append(chunks, strings.Join(chunk, "\n"))
+	}
+
+	return chunks
+}
+
+// FormatList renders all root routes grouped by category, split into pages of at most 2000 characters.
+func (TextHelp) FormatList(entries []HelpEntry) []Page {
+	var lines []string
+
+	categories, grouped := groupByCategory(entries)
+	for _, category := range categories {
+		if category != "" {
+			lines = append(lines, fmt.Sprintf("**%s**", category))
+		}
+		for _, e := range grouped[category] {
+			lines = append(lines, listLine(e))
+		}
+	}
+
+	return StringPages(packLines(lines, maxContentLength)...)
+}
+
+// FormatDetail renders a route with its usage, aliases, examples and subcommands.
+func (TextHelp) FormatDetail(entry HelpEntry) *discordgo.MessageSend {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "`%s`", entry.Invocation())
+	if entry.Meta.Description != "" {
+		fmt.Fprintf(&b, " - %s", entry.Meta.Description)
+	}
+	b.WriteString("\n")
+
+	for _, field := range detailFields(entry) {
+		fmt.Fprintf(&b, "**%s:** %s\n", field.Name, field.Value)
+	}
+
+	return &discordgo.MessageSend{Content: truncate(b.String(), maxContentLength)}
+}
+
+// FormatList renders all root routes in embeds with a field per category.
+//
+// Categories that do not fit in a field continue in the next field, and fields that do not fit in an embed
+// continue in the next embed, each being a page.
+func (f EmbedHelp) FormatList(entries []HelpEntry) []Page {
+	const title = "Commands"
+
+	var fields []*discordgo.MessageEmbedField
+
+	categories, grouped := groupByCategory(entries)
+	for _, category := range categories {
+		var lines []string
+		for _, e := range grouped[category] {
+			lines = append(lines, listLine(e))
+		}
+
+		name := category
+		if name == "" {
+			name = "General"
+		}
+		for i, value := range packLines(lines, maxEmbedFieldValueLength) {
+			field := &discordgo.MessageEmbedField{Name: name, Value: value}
+			if i > 0 {
+				field.Name += " (continued)"
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	var (
+		embeds []*discordgo.MessageEmbed
+		embed  *discordgo.MessageEmbed
+		size   int
+	)
+	for _, field := range fields {
+		n := utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		if embed == nil || len(embed.Fields) == maxEmbedFields || size+n > maxEmbedLength {
+			embed = &discordgo.MessageEmbed{Title: title, Color: f.Color}
+			embeds = append(embeds, embed)
+			size = len(title)
+		}
+		embed.Fields = append(embed.Fields, field)
+		size += n
+	}
+	if len(embeds) == 0 {
+		embeds = append(embeds, &discordgo.MessageEmbed{Title: title, Color: f.Color})
+	}
+
+	return EmbedPages(embeds...)
+}
+
+// FormatDetail renders a route in an embed with its usage, aliases, examples and subcommands.
+func (f EmbedHelp) FormatDetail(entry HelpEntry) *discordgo.MessageSend {
+	return &discordgo.MessageSend{Embed: &discordgo.MessageEmbed{
+		Title:       entry.Invocation(),
+		Description: truncate(entry.Meta.Description, maxEmbedDescriptionLength),
+		Color:       f.Color,
+		Fields:      detailFields(entry),
+	}}
+}
+
+// detailFields returns the fields describing a route in detail, omitting empty fields.
+// Values that are too long for an embed field are truncated.
+func detailFields(entry HelpEntry) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:  "Usage",
+		Value: fmt.Sprintf("`%s`", strings.TrimSpace(entry.Invocation()+" "+entry.Usage)),
+	})
+
+	if len(entry.Aliases) > 1 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Aliases", Value: strings.Join(entry.Aliases, ", ")})
+	}
+
+	if len(entry.Meta.Examples) > 0 {
+		examples := make([]string, 0, len(entry.Meta.Examples))
+		for _, ex := range entry.Meta.Examples {
+			examples = append(examples, fmt.Sprintf("`%s%s`", entry.Prefix, ex))
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Examples", Value: strings.Join(examples, "\n")})
+	}
+
+	if len(entry.Subcommands) > 0 {
+		subcommands := make([]string, 0, len(entry.Subcommands))
+		for _, sub := range entry.Subcommands {
+			subcommands = append(subcommands, sub.Path[len(sub.Path)-1])
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Subcommands", Value: strings.Join(subcommands, ", ")})
+	}
+
+	for _, field := range fields {
+		field.Value = truncate(field.Value, maxEmbedFieldValueLength)
+	}
+	return fields
+}
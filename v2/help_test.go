@@ -0,0 +1,117 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHelp_render(t *testing.T) {
+	router := New(nil)
+	help := NewHelp(router, TextHelp{})
+
+	router.Has(NewRoute(&testPref{}).On("help", "h").Do(help).Meta(Meta{Description: "shows help"}))
+	router.Has(NewRoute(&testPref{}).On("tag", "t").Do(&testCmd{}).
+		Meta(Meta{Description: "manages tags", Category: "Tags", Examples: []string{"tag create hi there"}}).
+		Has(
+			NewSubroute().On("create", "c").Do(&testCmd{}).
+				Args(Arg{Name: "name"}, Arg{Name: "content", Variadic: true}).
+				Flags(Flag{Long: "force", Short: 'f'}).
+				Meta(Meta{Description: "creates a tag"}),
+			NewSubroute().On("purge").Do(&testCmd{}).Meta(Meta{Hidden: true}),
+		))
+	router.Has(NewRoute(&testPref{}).On("secret").Do(&testCmd{}).Meta(Meta{Hidden: true}))
+	router.Has(NewRoute(nil).Do(&testCmd{}))
+
+	pages, err := help.render("guild_id_1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "`t!help` - shows help\n**Tags**\n`t!tag` - manages tags"
+	if len(pages) != 1 || pages[0].Content != expected {
+		t.Errorf("expected list %q, got %v", expected, pages)
+	}
+
+	pages, err = help.render("guild_id_1", []string{"T"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := pages[0]
+	for _, want := range []string{"`t!tag` - manages tags", "**Aliases:** tag, t", "`t!tag create hi there`", "**Subcommands:** create\n"} {
+		if !strings.Contains(msg.Content, want) {
+			t.Errorf("expected detail %q to contain %q", msg.Content, want)
+		}
+	}
+
+	pages, err = help.render("guild_id_1", []string{"tag", "c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "**Usage:** `t!tag create [--force] <name> <content...>`"; !strings.Contains(pages[0].Content, want) {
+		t.Errorf("expected detail %q to contain %q", pages[0].Content, want)
+	}
+
+	for _, path := range [][]string{{"secret"}, {"tag", "purge"}, {"nope"}} {
+		var notFound *HelpNotFoundError
+		if _, err = help.render("guild_id_1", path); !errors.As(err, &notFound) {
+			t.Errorf("%v: expected a *HelpNotFoundError, got %v", path, err)
+		}
+	}
+}
+
+func TestHelp_FormatList_limits(t *testing.T) {
+	var entries []HelpEntry
+	for i := 0; i < 400; i++ {
+		entries = append(entries, HelpEntry{
+			Prefix: "t!",
+			Path:   []string{fmt.Sprintf("command%d", i)},
+			Meta: Meta{
+				Description: strings.Repeat("describes a command ", 5),
+				Category:    fmt.Sprintf("Category %d", i%3),
+			},
+		})
+	}
+	entries[0].Meta.Description = strings.Repeat("a very long description ", 200)
+
+	listed := func(text string) int {
+		return strings.Count(text, "`t!command")
+	}
+
+	var count int
+	for _, page := range (TextHelp{}).FormatList(entries) {
+		if n := utf8.RuneCountInString(page.Content); n > maxContentLength {
+			t.Errorf("expected text pages of at most %d characters, got %d", maxContentLength, n)
+		}
+		count += listed(page.Content)
+	}
+	if count != len(entries) {
+		t.Errorf("expected text pages to list %d commands, got %d", len(entries), count)
+	}
+
+	count = 0
+	pages := (EmbedHelp{}).FormatList(entries)
+	if len(pages) < 2 {
+		t.Errorf("expected commands to span multiple embeds, got %d", len(pages))
+	}
+	for _, page := range pages {
+		size := utf8.RuneCountInString(page.Embed.Title)
+		if len(page.Embed.Fields) > maxEmbedFields {
+			t.Errorf("expected embeds of at most %d fields, got %d", maxEmbedFields, len(page.Embed.Fields))
+		}
+		for _, field := range page.Embed.Fields {
+			if n := utf8.RuneCountInString(field.Value); n > maxEmbedFieldValueLength {
+				t.Errorf("expected field values of at most %d characters, got %d", maxEmbedFieldValueLength, n)
+			}
+			size += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+			count += listed(field.Value)
+		}
+		if size > maxEmbedLength {
+			t.Errorf("expected embeds of at most %d characters, got %d", maxEmbedLength, size)
+		}
+	}
+	if count != len(entries) {
+		t.Errorf("expected embed pages to list %d commands, got %d", len(entries), count)
+	}
+}
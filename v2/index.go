@@ -0,0 +1,480 @@
+package v2
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// rootRoute is a Route that was added to the Router via Has or HasOnce.
+	rootRoute struct {
+		route *Route
+		seq   uint64 // registration order; a larger seq was added more recently
+		once  bool
+		fired uint32
+	}
+
+	// trieNode indexes routes by the lowercased alias path leading to them.
+	trieNode struct {
+		children map[string]*trieNode
+		matches  []match // ordered by a depth-first walk of each root route
+	}
+
+	// match is a route found by walking a trie path of length depth.
+	match struct {
+		root  *rootRoute
+		route *Route
+		path  []*Route // routes from the root route to route, inclusive
+		depth int
+	}
+
+	// routeGroup holds root routes sharing a Prefixer and a CmdParser so a message
+	// only needs to be prefix-trimmed and tokenized once for all of them.
+	routeGroup struct {
+		p        Prefixer
+		pid      int       // index of p in routeIndex.prefixers
+		parser   CmdParser // nil if the group uses the default parser of the index
+		roots    []*rootRoute
+		defaults []*rootRoute // roots with no aliases
+		trie     *trieNode
+	}
+
+	// routeIndex is an immutable snapshot of all root routes and dispatch options of a Router.
+	routeIndex struct {
+		groups           []*routeGroup
+		prefixers        []Prefixer // distinct Prefixers of all groups
+		parser           CmdParser  // if nil, falls back to cmdParserDefault
+		exclusive        bool
+		fallback         *routeIndex // only used if exclusive
+		suggest          *SuggestOptions
+		wrappers         []Wrapper      // middlewares of the Router
+		mention          bool           // if true, a mention of the bot is a prefix of routes with a Prefixer
+		edits            *time.Duration // edit window of the Router; nil unless every route handles edited messages
+		editable         bool           // if true, at least one route handles edited messages
+		reactions        []*reactionRoot
+		reactionWrappers []Wrapper   // reaction middlewares of the Router
+		pool             *workerPool // nil unless handlers run on a worker pool
+	}
+
+	// invocation is a route selected to handle a message.
+	//
+	// If err is non-nil, the message failed to parse and only the middlewares and Resolve of the root route will be run.
+	// depth is then the depth the message would have matched at if it were split on whitespace.
+	// If unknown is non-nil, no route matched and the unknown command is responded to; root and route are nil.
+	invocation struct {
+		ctx     context.Context
+		root    *rootRoute
+		route   *Route
+		path    []*Route
+		args    []string
+		depth   int
+		err     error
+		unknown *UnknownCommandError
+
+		suggest  *SuggestOptions
+		wrappers []Wrapper
+	}
+)
+
+// sameValue reports if a and b hold the same value.
+// Values that cannot be compared are treated as distinct.
+func sameValue(a, b interface{}) (same bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			same = false // comparable type holding an incomparable value, such as an interface field
+		}
+	}()
+	return a == b
+}
+
+// routeParser returns the CmdParser set on a route, or implemented by its Handler.
+// Returns nil if the route should use the default parser.
+func routeParser(route *Route) CmdParser {
+	if route.parser != nil {
+		return route.parser
+	}
+	if p, ok := route.h.(CmdParser); ok {
+		return p
+	}
+	return nil
+}
+
+// newRouteIndex builds an index out of root routes, which must be in registration order.
+//
+// parser is used by routes that do not have their own CmdParser, and may be nil.
+func newRouteIndex(roots []*rootRoute, parser CmdParser) *routeIndex {
+	idx := &routeIndex{parser: parser}
+
+	for _, root := range roots {
+		var (
+			g      *routeGroup
+			parser = routeParser(root.route)
+		)
+		for _, candidate := range idx.groups {
+			if sameValue(candidate.p, root.route.p) && sameValue(candidate.parser, parser) {
+				g = candidate
+				break
+			}
+		}
+		if g == nil {
+			g = &routeGroup{
+				p:      root.route.p,
+				pid:    idx.prefixerID(root.route.p),
+				parser: parser,
+				trie:   &trieNode{},
+			}
+			idx.groups = append(idx.groups, g)
+		}
+		g.add(root)
+		idx.editable = idx.editable || handlesEdits(root.route)
+	}
+
+	return idx
+}
+
+// prefixerID returns the index of p in the distinct prefixers of the index, adding it if not present.
+func (idx *routeIndex) prefixerID(p Prefixer) int {
+	for i, candidate := range idx.prefixers {
+		if sameValue(candidate, p) {
+			return i
+		}
+	}
+	idx.prefixers = append(idx.prefixers, p)
+	return len(idx.prefixers) - 1
+}
+
+// child returns the node at the given token, creating it if it does not exist.
+func (n *trieNode) child(tok string) *trieNode {
+	if n.children == nil {
+		n.children = map[string]*trieNode{}
+	}
+	c, ok := n.children[tok]
+	if !ok {
+		c = &trieNode{}
+		n.children[tok] = c
+	}
+	return c
+}
+
+// add inserts a root route and all of its aliased subroutes into the group.
+func (g *routeGroup) add(root *rootRoute) {
+	g.roots = append(g.roots, root)
+	if root.route.IsDefault() {
+		g.defaults = append(g.defaults, root)
+		return
+	}
+	g.trie.insert(root, root.route, nil, 1)
+}
+
+// insert adds route under each of its aliases, then recurses into its subroutes.
+//
+// Insertion follows the same depth-first order findRouteRecursive searches in,
+// so the last match stored in a node is the one findRouteRecursive would select.
+func (n *trieNode) insert(root *rootRoute, route *Route, parents []*Route, depth int) {
+	path := append(parents[:len(parents):len(parents)], route)
+
+	seen := make(map[string]bool, len(route.aliases))
+	for _, alias := range route.aliases {
+		alias = strings.ToLower(alias)
+		if seen[alias] {
+			continue
+		}
+		seen[alias] = true
+
+		c := n.child(alias)
+		c.matches = append(c.matches, match{root: root, route: route, path: path, depth: depth})
+		for _, sr := range route.subroutes {
+			if !sr.IsDefault() {
+				c.insert(root, sr, path, depth+1)
+			}
+		}
+	}
+}
+
+// find returns the deepest route matching args for every root route in the group, ordered by registration.
+//
+// Equivalent to running findRouteRecursive on each root route, but args are only walked once.
+func (g *routeGroup) find(args []string) []match {
+	if len(args) == 0 {
+		return nil
+	}
+
+	best := map[*rootRoute]match{}
+	for _, root := range g.defaults {
+		best[root] = match{root: root, route: root.route, path: []*Route{root.route}}
+	}
+
+	n := g.trie
+	for _, tok := range args {
+		if n = n.children[strings.ToLower(tok)]; n == nil {
+			break
+		}
+		for _, m := range n.matches {
+			best[m.root] = m // deeper nodes and later siblings overwrite earlier matches
+		}
+	}
+
+	matches := make([]match, 0, len(best))
+	for _, root := range g.roots {
+		if m, ok := best[root]; ok {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// parse tokenizes a command with the parser of the group, or the default parser of the index.
+func (idx *routeIndex) parse(g *routeGroup, cmd string) ([]string, error) {
+	switch {
+	case g.parser != nil:
+		return g.parser.Parse(cmd)
+	case idx.parser != nil:
+		return idx.parser.Parse(cmd)
+	default:
+		return cmdParserDefault(cmd), nil
+	}
+}
+
+// invocations returns every route that should handle the message in ctx.
+//
+// ctx must contain the message.
+func (idx *routeIndex) invocations(ctx context.Context) []*invocation {
+	var (
+		invs     []*invocation
+		msg      = utils.GetMsg(ctx)
+		prefixes = make([]*prefixSet, len(idx.prefixers)) // each Prefixer is loaded at most once per message
+	)
+
+	for _, g := range idx.groups {
+		if prefixes[g.pid] == nil {
+			ps := loadPrefixes(g.p, msg.GuildID)
+			prefixes[g.pid] = &ps
+		}
+		prefix, cmd, ok := idx.trimPrefix(ctx, g, *prefixes[g.pid])
+		if !ok {
+			continue
+		}
+		gctx := utils.WithPrefix(ctx, prefix)
+
+		args, err := idx.parse(g, cmd)
+		if err != nil {
+			// the depth of the route the message would match if split on whitespace hints at the intended route.
+			// Only root routes whose aliases match resolve the error, so a stray quote does not reach every route.
+			depths := map[*rootRoute]int{}
+			for _, m := range g.find(cmdParserDefault(cmd)) {
+				if m.depth > depths[m.root] {
+					depths[m.root] = m.depth
+				}
+			}
+			for _, root := range g.roots {
+				if depths[root] == 0 {
+					continue
+				}
+				invs = append(invs, &invocation{
+					ctx: gctx, root: root, route: root.route, path: []*Route{root.route}, depth: depths[root], err: err,
+					wrappers: idx.wrappers,
+				})
+			}
+			continue
+		}
+
+		for _, m := range g.find(args) {
+			invs = append(invs, &invocation{
+				ctx: gctx, root: m.root, route: m.route, path: m.path, args: args, depth: m.depth,
+				suggest: idx.suggest, wrappers: idx.wrappers,
+			})
+		}
+	}
+
+	return invs
+}
+
+// trimPrefix trims the prefix of a group from the content of the message in ctx.
+// ps holds the prefixes the Prefixer of the group loaded for the guild of the message.
+//
+// If mention prefixes are enabled and the group has a Prefixer, a mention of the bot followed by optional whitespace
+// takes priority over ps. Returns the prefix that was matched along with the command.
+func (idx *routeIndex) trimPrefix(ctx context.Context, g *routeGroup, ps prefixSet) (string, string, bool) {
+	msg := utils.GetMsg(ctx)
+
+	if idx.mention && g.p != nil {
+		if mention, cmd, ok := trimMention(utils.GetSes(ctx), msg.Content); ok {
+			return mention, cmd, true
+		}
+	}
+
+	return ps.trim(msg.Content)
+}
+
+// trimMention trims a leading mention of the bot (<@id> or <@!id>) and any whitespace following it.
+// Returns the mention as written along with the command.
+func trimMention(s *discordgo.Session, content string) (string, string, bool) {
+	if s == nil || s.State == nil || s.State.User == nil {
+		return "", "", false
+	}
+
+	for _, mention := range []string{"<@" + s.State.User.ID + ">", "<@!" + s.State.User.ID + ">"} {
+		if !strings.HasPrefix(content, mention) {
+			continue
+		}
+		cmd := strings.TrimLeftFunc(content[len(mention):], unicode.IsSpace)
+		return mention, cmd, cmd != ""
+	}
+	return "", "", false
+}
+
+// best returns the invocation with the deepest matching route, or nil if no route matched.
+// Only invocations with a parse error are considered if failed is true, otherwise they are ignored.
+// Ties are broken by the most recently added root route.
+func best(invs []*invocation, failed bool) *invocation {
+	var selected *invocation
+	for _, inv := range invs {
+		if (inv.err != nil) != failed || inv.unknown != nil {
+			continue
+		}
+		if selected == nil ||
+			inv.depth > selected.depth ||
+			(inv.depth == selected.depth && inv.root.seq > selected.root.seq) {
+			selected = inv
+		}
+	}
+	return selected
+}
+
+// claim reports if the invocation may run. Routes added with HasOnce can only be claimed once.
+func (inv *invocation) claim() bool {
+	if inv.root == nil || !inv.root.once {
+		return true
+	}
+	return atomic.CompareAndSwapUint32(&inv.root.fired, 0, 1)
+}
+
+// middlewares returns the middlewares of the Router followed by the middlewares inherited by the matched route.
+func (inv *invocation) middlewares() []Wrapper {
+	return append(inv.wrappers[:len(inv.wrappers):len(inv.wrappers)], inheritedMiddlewares(inv.path)...)
+}
+
+// run executes the Handler of the matched route with an accumulated context.
+func (inv *invocation) run() {
+	if inv.unknown != nil {
+		inv.respond()
+		return
+	}
+
+	var (
+		ctx  = inv.ctx
+		root = inv.root.route
+	)
+
+	if inv.err != nil {
+		if root.h == nil {
+			return
+		}
+		if drop, err := checkFilters(ctx, inv.path); err != nil {
+			if !drop {
+				handleResolve(root.h)(utils.WithErr(ctx, err))
+			}
+			return
+		}
+		if required, ok := requiredPermissions(inv.path); ok {
+			if err := checkPermissions(ctx, required); err != nil {
+				handleResolve(root.h)(utils.WithErr(ctx, err))
+				return
+			}
+		}
+
+		// the parse error passes through the middlewares so they can reject the message first
+		fail := func(context.Context) error { return inv.err }
+		err := chainMiddlewares(inv.middlewares(), fail)(ctx)
+		if err == nil {
+			return // a middleware handled the message instead
+		}
+		handleResolve(root.h)(utils.WithErr(ctx, err))
+		return
+	}
+
+	route := inv.route
+	if route.h == nil {
+		return
+	}
+
+	args := inv.args[inv.depth:]
+	ctx = utils.WithArgs(utils.WithAlias(ctx, inv.args[:inv.depth]), args)
+
+	if drop, err := checkFilters(ctx, inv.path); err != nil {
+		if !drop {
+			handleResolve(route.h)(utils.WithErr(ctx, err))
+		}
+		return
+	}
+
+	if required, ok := requiredPermissions(inv.path); ok {
+		if err := checkPermissions(ctx, required); err != nil {
+			handleResolve(route.h)(utils.WithErr(ctx, err))
+			return
+		}
+	}
+
+	// flags are parsed after the middlewares, and passed on to Resolve along with the remaining args once parsed
+	var (
+		flags utils.Flags
+		rest  = args
+	)
+	handle := func(ctx context.Context) error {
+		if inv.suggest != nil {
+			if unknown := unknownSubcommand(ctx, inv.path, inv.args, inv.depth, inv.suggest); unknown != nil {
+				return unknown
+			}
+		}
+		args := args
+		if len(route.flags) != 0 {
+			positional, parsed, err := parseFlags(route.flags, args)
+			if err != nil {
+				return err
+			}
+			args, rest, flags = positional, positional, parsed
+			ctx = utils.WithArgs(utils.WithFlags(ctx, flags), args)
+		}
+		if len(route.args) != 0 {
+			values, err := convertArgs(ctx, route.args, args)
+			if err != nil {
+				return err
+			}
+			ctx = utils.WithArgValues(ctx, values)
+		}
+		if route.confirm != nil {
+			if err := confirm(ctx, route.confirm); err != nil {
+				return err
+			}
+		}
+		return route.h.Handle(ctx)
+	}
+
+	err := chainMiddlewares(inv.middlewares(), handle)(ctx)
+	if flags != nil {
+		ctx = utils.WithArgs(utils.WithFlags(ctx, flags), rest)
+	}
+	if err != nil {
+		ctx = utils.WithErr(ctx, err)
+	}
+	if unknown, ok := err.(*UnknownCommandError); ok && inv.suggest != nil && inv.suggest.Respond != nil {
+		inv.suggest.Respond(ctx, unknown)
+		return
+	}
+
+	handleResolve(route.h)(ctx)
+}
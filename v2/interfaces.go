@@ -26,6 +26,21 @@ type (
 		Do(ctx context.Context) error
 	}
 
+	// HandleFunc is the signature of Handle. It is the unit of work wrapped by a Wrapper.
+	HandleFunc func(ctx context.Context) error
+
+	// Wrapper is a middleware that wraps the rest of the middleware chain and Handle.
+	//
+	// Wrap accepts the next HandleFunc in the chain and returns a HandleFunc that calls it.
+	// Unlike Middlewarer, a Wrapper can replace the context passed downstream, run code after Handle returns
+	// (such as timing or recovering panics), and observe or replace the returned error.
+	Wrapper interface {
+		Wrap(next HandleFunc) HandleFunc
+	}
+
+	// WrapperFunc is an adapter to allow the use of an ordinary function as a Wrapper.
+	WrapperFunc func(next HandleFunc) HandleFunc
+
 	// Prefixer identifies the prefix based on the guildID and removes the prefix of the command string if matched.
 	//
 	// Load fetches a prefix that matches the guildID and returns the prefix mapped to the guildID with an ok bool.
@@ -36,6 +51,26 @@ type (
 		Default() string
 	}
 
+	// MultiPrefixer is a Prefixer that accepts multiple prefixes per guild, such as "!" and "?".
+	//
+	// LoadAll fetches all prefixes of the guildID with an ok bool. If ok is false, Default is the only prefix.
+	// Load should return the primary prefix, which is shown in help.
+	//
+	// PrefixOptions controls how the prefixes are matched. When several prefixes match a message, the longest one wins.
+	MultiPrefixer interface {
+		Prefixer
+		LoadAll(guildID string) ([]string, bool)
+		PrefixOptions() PrefixOptions
+	}
+
+	// PrefixOptions controls how the prefixes of a MultiPrefixer are matched.
+	PrefixOptions struct {
+		// CaseInsensitive matches prefixes regardless of case, so "bot " matches "Bot help".
+		CaseInsensitive bool
+		// TrimSpace allows whitespace between the prefix and the command, so "bot" matches both "bothelp" and "bot help".
+		TrimSpace bool
+	}
+
 	// Handler is bound to a route and will be called when handling Discord's Message Create events.
 	// https://discord.com/developers/docs/topics/gateway#message-create
 	//
@@ -54,3 +89,25 @@ type (
 		Resolve(ctx context.Context)
 	}
 )
+
+// Handle calls f(ctx).
+func (f HandleFunc) Handle(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Wrap calls f(next).
+func (f WrapperFunc) Wrap(next HandleFunc) HandleFunc {
+	return f(next)
+}
+
+// Adapt converts a Middlewarer into a Wrapper that calls next only if Do returns a nil error.
+func Adapt(m Middlewarer) Wrapper {
+	return WrapperFunc(func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context) error {
+			if err := m.Do(ctx); err != nil {
+				return err
+			}
+			return next(ctx)
+		}
+	})
+}
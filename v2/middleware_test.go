@@ -0,0 +1,166 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+type testCtxKey struct{}
+
+// testMiddleware records its execution and fails if err is non-nil.
+type testMiddleware struct {
+	name  string
+	trace *[]string
+	err   error
+}
+
+func (m *testMiddleware) Do(ctx context.Context) error {
+	*m.trace = append(*m.trace, m.name)
+	return m.err
+}
+
+func TestRoute_Wrap(t *testing.T) {
+	var (
+		ses      = makeMockSes()
+		router   = New(ses)
+		trace    []string
+		resolved error
+	)
+
+	wrapper := func(name string) Wrapper {
+		return WrapperFunc(func(next HandleFunc) HandleFunc {
+			return func(ctx context.Context) error {
+				trace = append(trace, name+" before")
+				err := next(context.WithValue(ctx, testCtxKey{}, name))
+				trace = append(trace, name+" after")
+				if err != nil {
+					return errors.New(name + ": " + err.Error())
+				}
+				return nil
+			}
+		})
+	}
+
+	failing := &testMiddleware{name: "failing", trace: &trace}
+
+	router.Has(NewRoute(&testPref{}).On("wrap").
+		Use(&testMiddleware{name: "first", trace: &trace}).
+		Wrap(wrapper("outer"), wrapper("inner")).
+		Use(failing).
+		Do(&testCmd{
+			HandleCallback: func(ctx context.Context) error {
+				trace = append(trace, "handle "+ctx.Value(testCtxKey{}).(string))
+				return errors.New("handle failed")
+			},
+			ResolveCallback: func(ctx context.Context) {
+				resolved = utils.GetErr(ctx)
+				if ctx.Value(testCtxKey{}) != nil {
+					t.Error("expected Resolve to receive the context the chain was entered with")
+				}
+			},
+		}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"wrap"))
+	expected := []string{"first", "outer before", "inner before", "failing", "handle inner", "inner after", "outer after"}
+	if !strSliceEqual(expected, trace, false) {
+		t.Errorf("expected middleware trace %v, got %v", expected, trace)
+	}
+	if resolved == nil || resolved.Error() != "outer: inner: handle failed" {
+		t.Errorf("expected wrapped error to be resolved, got %v", resolved)
+	}
+
+	trace = nil
+	failing.err = errors.New("denied")
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"wrap"))
+	expected = []string{"first", "outer before", "inner before", "failing", "inner after", "outer after"}
+	if !strSliceEqual(expected, trace, false) {
+		t.Errorf("expected middleware trace %v, got %v", expected, trace)
+	}
+	if resolved == nil || resolved.Error() != "outer: inner: denied" {
+		t.Errorf("expected adapted middleware error to be resolved, got %v", resolved)
+	}
+}
+
+func TestRoute_inheritMiddlewares(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		trace  []string
+	)
+
+	mw := func(name string) *testMiddleware {
+		return &testMiddleware{name: name, trace: &trace}
+	}
+
+	router.Use(mw("router"))
+	router.Has(NewRoute(&testPref{}).On("admin").Do(&testCmd{}).Use(mw("admin")).Has(
+		NewSubroute().On("ban").Do(&testCmd{}).Use(mw("ban")).Has(
+			NewSubroute().On("user").Do(&testCmd{}),
+		),
+		NewSubroute().On("help").Do(&testCmd{}).Use(mw("help")).Isolate().Has(
+			NewSubroute().On("ban").Do(&testCmd{}).Use(mw("help ban")),
+		),
+	))
+	router.Wrap(Adapt(mw("router wrap")))
+
+	testCases := []struct {
+		content  string
+		expected []string
+	}{
+		{content: "admin", expected: []string{"router", "router wrap", "admin"}},
+		{content: "admin ban user", expected: []string{"router", "router wrap", "admin", "ban"}},
+		{content: "admin help", expected: []string{"router", "router wrap", "help"}},
+		{content: "admin help ban", expected: []string{"router", "router wrap", "help", "help ban"}},
+	}
+
+	for _, c := range testCases {
+		trace = nil
+		router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+c.content))
+		if !strSliceEqual(c.expected, trace, false) {
+			t.Errorf("%q: expected middleware trace %v, got %v", c.content, c.expected, trace)
+		}
+	}
+}
+
+func TestRouter_middlewaresParseError(t *testing.T) {
+	var (
+		ses      = makeMockSes()
+		router   = New(ses)
+		trace    []string
+		resolved error
+		denied   = errors.New("denied")
+	)
+
+	router.Use(&testMiddleware{name: "router", trace: &trace})
+	router.Has(NewRoute(&testPref{}).On("quote").Use(&testMiddleware{name: "quote", trace: &trace}).Do(&testCmd{
+		ParseCallback: ShellParser{}.Parse,
+		HandleCallback: func(ctx context.Context) error {
+			t.Error("expected Handle not to run when the message fails to parse")
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			resolved = utils.GetErr(ctx)
+		},
+	}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+`quote "unterminated`))
+	if expected := []string{"router", "quote"}; !strSliceEqual(expected, trace, false) {
+		t.Errorf("expected middleware trace %v, got %v", expected, trace)
+	}
+	if _, ok := resolved.(*ParseError); !ok {
+		t.Errorf("expected *ParseError to be resolved, got %v", resolved)
+	}
+
+	trace, resolved = nil, nil
+	router.Use(&testMiddleware{name: "blacklist", trace: &trace, err: denied})
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+`quote "unterminated`))
+	if expected := []string{"router", "blacklist"}; !strSliceEqual(expected, trace, false) {
+		t.Errorf("expected middleware trace %v, got %v", expected, trace)
+	}
+	if resolved != denied {
+		t.Errorf("expected middleware error to be resolved before the parse error, got %v", resolved)
+	}
+}
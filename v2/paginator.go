@@ -0,0 +1,198 @@
+package v2
+
+import (
+	"context"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Default navigation emojis of a Paginator.
+const (
+	PageFirst = "⏮️"
+	PagePrev  = "◀️"
+	PageNext  = "▶️"
+	PageLast  = "⏭️"
+	PageStop  = "⏹️"
+)
+
+// defaultPageTimeout is how long a Paginator waits for a reaction if its Timeout is not set.
+const defaultPageTimeout = 2 * time.Minute
+
+// Page is a page of a Paginator, made of content, an embed, or both.
+type Page struct {
+	Content string
+	Embed   *discordgo.MessageEmbed
+}
+
+// StringPages returns a Page with the content of each string.
+func StringPages(contents ...string) []Page {
+	pages := make([]Page, 0, len(contents))
+	for _, c := range contents {
+		pages = append(pages, Page{Content: c})
+	}
+	return pages
+}
+
+// EmbedPages returns a Page with each embed.
+func EmbedPages(embeds ...*discordgo.MessageEmbed) []Page {
+	pages := make([]Page, 0, len(embeds))
+	for _, e := range embeds {
+		pages = append(pages, Page{Embed: e})
+	}
+	return pages
+}
+
+// Paginator sends the first of its pages in reply to a message, and lets the author of the message navigate the pages
+// with reactions, such as for leaderboards or search results.
+//
+// The emojis of the Paginator can be changed before it runs; an empty emoji disables its action.
+// Only reactions added by the author are handled, and are removed if the bot has the Manage Messages permission,
+// so an emoji can be used again.
+type Paginator struct {
+	Pages []Page
+
+	// Timeout is how long the Paginator waits for a reaction before tearing itself down.
+	// If it is not set, the Paginator waits two minutes.
+	Timeout time.Duration
+
+	First, Prev, Next, Last, Stop string
+}
+
+// NewPaginator returns a Paginator of pages with the default emojis and a timeout of two minutes.
+func NewPaginator(pages ...Page) *Paginator {
+	return &Paginator{
+		Pages:   pages,
+		Timeout: defaultPageTimeout,
+		First:   PageFirst,
+		Prev:    PagePrev,
+		Next:    PageNext,
+		Last:    PageLast,
+		Stop:    PageStop,
+	}
+}
+
+// Run sends the first page in the channel of the message in ctx, and handles navigation until the Paginator is idle
+// for Timeout, the Stop emoji is added, ctx is done or the Router is closed. The navigation reactions are then removed.
+//
+// Run blocks until the Paginator is torn down. It is meant to be called from Handle, and returns ErrNoRouter
+// if ctx was not created by a Router. A single page is sent without navigation; otherwise, Run returns ErrAwaitDeadlock
+// without sending anything if the handler of ctx blocks the session from receiving events.
+func (p *Paginator) Run(ctx context.Context) error {
+	r := RouterFromContext(ctx)
+	if r == nil {
+		return ErrNoRouter
+	}
+	ses, msg := utils.GetSes(ctx), utils.GetMsg(ctx)
+	if msg == nil || msg.Author == nil {
+		return ErrNoMessage
+	}
+	if len(p.Pages) == 0 {
+		return nil
+	}
+	if len(p.Pages) > 1 {
+		if err := r.awaitable(ctx); err != nil {
+			return err
+		}
+	}
+
+	sent, err := ses.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Content: p.Pages[0].Content,
+		Embed:   p.Pages[0].Embed,
+	})
+	if err != nil {
+		return err
+	}
+	if len(p.Pages) == 1 {
+		return nil
+	}
+
+	w, err := r.subscribe(func(ctx context.Context) bool {
+		reaction := utils.GetReaction(ctx)
+		return reaction != nil && !utils.IsReactionRemoved(ctx) &&
+			reaction.MessageID == sent.ID && reaction.UserID == msg.Author.ID
+	}, len(p.emojis()), false)
+	if err != nil {
+		return err
+	}
+	defer r.unsubscribe(w)
+	defer func() { _ = ses.MessageReactionsRemoveAll(sent.ChannelID, sent.ID) }()
+
+	for _, emoji := range p.emojis() {
+		if err := ses.MessageReactionAdd(sent.ChannelID, sent.ID, emoji); err != nil {
+			return err
+		}
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultPageTimeout
+	}
+	idle := time.NewTimer(timeout)
+	defer idle.Stop()
+
+	for page := 0; ; {
+		select {
+		case ectx := <-w.events:
+			reaction := utils.GetReaction(ectx)
+			_ = ses.MessageReactionRemove(sent.ChannelID, sent.ID, reaction.Emoji.APIName(), reaction.UserID)
+
+			next, stop := p.navigate(reaction.Emoji, page)
+			if stop {
+				return nil
+			}
+			if next != page {
+				page = next
+				content, embed := p.Pages[page].Content, p.Pages[page].Embed
+				if _, err := ses.ChannelMessageEditComplex(&discordgo.MessageEdit{
+					ID: sent.ID, Channel: sent.ChannelID, Content: &content, Embed: embed,
+				}); err != nil {
+					return err
+				}
+			}
+
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(timeout)
+		case <-idle.C:
+			return nil
+		case <-w.closed:
+			return ErrRouterClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// emojis returns the enabled navigation emojis, in the order they are added to the message.
+func (p *Paginator) emojis() []string {
+	var emojis []string
+	for _, e := range []string{p.First, p.Prev, p.Next, p.Last, p.Stop} {
+		if e != "" {
+			emojis = append(emojis, e)
+		}
+	}
+	return emojis
+}
+
+// navigate returns the page to show after emoji is added on page, and true if the Paginator should stop.
+// Navigation wraps around the first and last pages.
+func (p *Paginator) navigate(emoji discordgo.Emoji, page int) (int, bool) {
+	n := len(p.Pages)
+	switch {
+	case p.Stop != "" && matchesEmoji(p.Stop, emoji):
+		return page, true
+	case p.First != "" && matchesEmoji(p.First, emoji):
+		return 0, false
+	case p.Prev != "" && matchesEmoji(p.Prev, emoji):
+		return (page - 1 + n) % n, false
+	case p.Next != "" && matchesEmoji(p.Next, emoji):
+		return (page + 1) % n, false
+	case p.Last != "" && matchesEmoji(p.Last, emoji):
+		return n - 1, false
+	}
+	return page, false
+}
@@ -0,0 +1,166 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPaginator_navigate(t *testing.T) {
+	p := NewPaginator(StringPages("a", "b", "c")...)
+	p.First = ""
+
+	tests := []struct {
+		emoji    string
+		page     int
+		expected int
+		stop     bool
+	}{
+		{emoji: PageNext, page: 0, expected: 1},
+		{emoji: PageNext, page: 2, expected: 0},
+		{emoji: PagePrev, page: 0, expected: 2},
+		{emoji: PageLast, page: 0, expected: 2},
+		{emoji: PageFirst, page: 2, expected: 2},
+		{emoji: "👍", page: 1, expected: 1},
+		{emoji: PageStop, page: 1, expected: 1, stop: true},
+	}
+
+	for _, tt := range tests {
+		page, stop := p.navigate(discordgo.Emoji{Name: tt.emoji}, tt.page)
+		if page != tt.expected || stop != tt.stop {
+			t.Errorf("%s on page %d: expected page %d (stop=%v), got %d (stop=%v)",
+				tt.emoji, tt.page, tt.expected, tt.stop, page, stop)
+		}
+	}
+
+	if emojis := p.emojis(); !strSliceEqual([]string{PagePrev, PageNext, PageLast, PageStop}, emojis, false) {
+		t.Errorf("expected disabled emojis to be skipped, got %v", emojis)
+	}
+}
+
+func TestPaginator_Run(t *testing.T) {
+	var (
+		prompted = make(chan struct{})
+		edits    = make(chan string, 1)
+	)
+	ses, srv := makeMockRESTSes(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost: // the first page
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "message_id_2", "channel_id": "channel_id_1"}`))
+		case http.MethodPatch: // a page is shown
+			var edit struct{ Content string }
+			_ = json.NewDecoder(req.Body).Decode(&edit)
+			edits <- edit.Content
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "message_id_2", "channel_id": "channel_id_1"}`))
+		case http.MethodPut: // the Stop emoji is added last, once the Paginator listens for reactions
+			w.WriteHeader(http.StatusNoContent)
+			if strings.Contains(req.URL.Path, PageStop) {
+				close(prompted)
+			}
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer srv.Close()
+
+	var (
+		router = New(ses)
+		done   = make(chan error, 1)
+		// a Paginator with no Timeout waits for the default timeout
+		p = &Paginator{Pages: StringPages("a", "b", "c"), Next: PageNext, Stop: PageStop}
+	)
+
+	router.Has(NewRoute(&testPref{}).On("top").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		done <- p.Run(ctx)
+		return nil
+	}}))
+
+	msg := makeMockMsg(testDefaultPrefix + "top")
+	msg.ChannelID = "channel_id_1"
+	go router.onMessageCreate(ses, msg)
+
+	select {
+	case <-prompted:
+	case <-time.After(time.Second):
+		t.Fatal("expected the first page to be sent")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected the Paginator to wait for reactions, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	react := func(userID, emoji string) {
+		reaction := makeMockReaction(userID, "message_id_2", discordgo.Emoji{Name: emoji})
+		router.onMessageReactionAdd(ses, &discordgo.MessageReactionAdd{MessageReaction: reaction})
+	}
+
+	react("author_id_2", PageNext) // only the author navigates
+	react("author_id_1", PageNext)
+	select {
+	case content := <-edits:
+		if content != "b" {
+			t.Errorf("expected the second page to be shown, got %q", content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the second page to be shown")
+	}
+
+	react("author_id_1", PageStop)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected the Paginator to stop without error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the Paginator to stop")
+	}
+	select {
+	case content := <-edits:
+		t.Errorf("expected only one page to be shown, got %q", content)
+	default:
+	}
+}
+
+func TestRouter_subscribe(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		emoji  = discordgo.Emoji{Name: PageNext}
+	)
+
+	w, err := router.subscribe(func(ctx context.Context) bool {
+		reaction := utils.GetReaction(ctx)
+		return reaction != nil && reaction.MessageID == "message_id_1"
+	}, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router.dispatchReaction(ses, makeMockReaction("author_id_1", "message_id_1", emoji), ReactionAdd)
+	router.dispatchReaction(ses, makeMockReaction("author_id_1", "message_id_2", emoji), ReactionAdd)
+	router.dispatchReaction(ses, makeMockReaction("self_id_1", "message_id_1", emoji), ReactionAdd)
+	router.dispatchReaction(ses, makeMockReaction("author_id_1", "message_id_1", emoji), ReactionRemove)
+	router.dispatchReaction(ses, makeMockReaction("author_id_1", "message_id_1", emoji), ReactionAdd)
+
+	if n := len(w.events); n != 2 {
+		t.Errorf("expected buffer to drop events when full, got %d events", n)
+	}
+	if n := router.waiting(); n != 1 {
+		t.Errorf("expected waiter to remain subscribed, got %d waiters", n)
+	}
+
+	router.unsubscribe(w)
+	if n := router.waiting(); n != 0 {
+		t.Errorf("expected waiter to be unsubscribed, got %d waiters", n)
+	}
+}
@@ -0,0 +1,142 @@
+package v2
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const codeBlockDelim = "```"
+
+// ParseError is returned by ShellParser when a command cannot be tokenized.
+type ParseError struct {
+	Pos    int // byte offset of the error in the command, not including the prefix
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at position %d: %s", e.Pos, e.Reason)
+}
+
+// ShellParser is a CmdParser that tokenizes a command similar to a POSIX shell.
+//
+// Tokens are separated by whitespace, with the following exceptions:
+//
+// Text in double quotes is one token. A backslash inside double quotes only escapes a double quote or another backslash.
+//
+// Text in single quotes is one token and is kept exactly as written. A single quote only starts quoted text
+// at the beginning of a token, so apostrophes within words such as "don't" are kept as written.
+//
+// A backslash outside of quotes escapes the following character.
+//
+// Discord code blocks (```) and inline code (`) are kept as part of a single token with their backticks intact.
+//
+// Quotes that are not closed, unclosed code and trailing backslashes return a *ParseError.
+type ShellParser struct{}
+
+// Parse tokenizes the command.
+func (ShellParser) Parse(cmd string) ([]string, error) {
+	var (
+		toks    []string
+		tok     strings.Builder
+		inTok   bool // distinguishes an empty quoted token from no token
+		pos     int
+		cmdSize = len(cmd)
+	)
+
+	flush := func() {
+		if inTok {
+			toks = append(toks, tok.String())
+		}
+		tok.Reset()
+		inTok = false
+	}
+
+	for pos < cmdSize {
+		r, size := utf8.DecodeRuneInString(cmd[pos:])
+
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+			pos += size
+
+		case strings.HasPrefix(cmd[pos:], codeBlockDelim):
+			end := strings.Index(cmd[pos+len(codeBlockDelim):], codeBlockDelim)
+			if end == -1 {
+				return nil, &ParseError{Pos: pos, Reason: "unterminated code block"}
+			}
+			end += pos + 2*len(codeBlockDelim)
+			tok.WriteString(cmd[pos:end])
+			inTok = true
+			pos = end
+
+		case r == '`':
+			end := strings.IndexRune(cmd[pos+size:], '`')
+			if end == -1 {
+				return nil, &ParseError{Pos: pos, Reason: "unterminated inline code"}
+			}
+			end += pos + 2*size
+			tok.WriteString(cmd[pos:end])
+			inTok = true
+			pos = end
+
+		case r == '\'' && !inTok: // apostrophes within a word, such as in "don't", are literal
+			end := strings.IndexRune(cmd[pos+size:], '\'')
+			if end == -1 {
+				return nil, &ParseError{Pos: pos, Reason: "unterminated single quote"}
+			}
+			tok.WriteString(cmd[pos+size : pos+size+end])
+			inTok = true
+			pos += end + 2*size
+
+		case r == '"':
+			end, err := readDoubleQuoted(cmd, pos, &tok)
+			if err != nil {
+				return nil, err
+			}
+			inTok = true
+			pos = end
+
+		case r == '\\':
+			if pos+size >= cmdSize {
+				return nil, &ParseError{Pos: pos, Reason: "trailing backslash"}
+			}
+			escaped, escapedSize := utf8.DecodeRuneInString(cmd[pos+size:])
+			tok.WriteRune(escaped)
+			inTok = true
+			pos += size + escapedSize
+
+		default:
+			tok.WriteRune(r)
+			inTok = true
+			pos += size
+		}
+	}
+	flush()
+
+	if toks == nil {
+		toks = []string{}
+	}
+	return toks, nil
+}
+
+// readDoubleQuoted writes the contents of the double quoted string starting at start into tok,
+// and returns the position following the closing quote.
+func readDoubleQuoted(cmd string, start int, tok *strings.Builder) (int, error) {
+	for pos := start + 1; pos < len(cmd); {
+		r, size := utf8.DecodeRuneInString(cmd[pos:])
+
+		switch {
+		case r == '"':
+			return pos + size, nil
+		case r == '\\' && pos+size < len(cmd) && (cmd[pos+size] == '"' || cmd[pos+size] == '\\'):
+			tok.WriteByte(cmd[pos+size])
+			pos += size + 1
+		default:
+			tok.WriteRune(r)
+			pos += size
+		}
+	}
+	return 0, &ParseError{Pos: start, Reason: "unterminated double quote"}
+}
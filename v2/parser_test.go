@@ -0,0 +1,150 @@
+package v2
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+func TestShellParser_Parse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cmd      string
+		expected []string
+		errPos   int // -1 if no error is expected
+	}{
+		{name: "whitespace", cmd: "  tag   create\thello ", expected: []string{"tag", "create", "hello"}, errPos: -1},
+		{name: "empty", cmd: "   ", expected: []string{}, errPos: -1},
+		{name: "double quotes", cmd: `tag create "hello world"`, expected: []string{"tag", "create", "hello world"}, errPos: -1},
+		{name: "escaped double quote", cmd: `say "a \"b\" \c"`, expected: []string{"say", `a "b" \c`}, errPos: -1},
+		{name: "single quotes are literal", cmd: `say 'a \"b\"'`, expected: []string{"say", `a \"b\"`}, errPos: -1},
+		{name: "empty quotes", cmd: `say "" ''`, expected: []string{"say", "", ""}, errPos: -1},
+		{name: "adjacent quotes join", cmd: `say 'a b'"c d"foo`, expected: []string{"say", "a bc dfoo"}, errPos: -1},
+		{name: "apostrophe", cmd: `say don't do it's`, expected: []string{"say", "don't", "do", "it's"}, errPos: -1},
+		{name: "apostrophe after quotes", cmd: `say foo"bar baz"'!'`, expected: []string{"say", "foobar baz'!'"}, errPos: -1},
+		{name: "apostrophe in quotes", cmd: `say "it's" 'don'`, expected: []string{"say", "it's", "don"}, errPos: -1},
+		{name: "backslash escape", cmd: `say hello\ world \"`, expected: []string{"say", "hello world", `"`}, errPos: -1},
+		{name: "inline code", cmd: "run `x := 1` now", expected: []string{"run", "`x := 1`", "now"}, errPos: -1},
+		{name: "code block", cmd: "run ```go\nfmt.Println(\"hi there\")\n``` now", expected: []string{"run", "```go\nfmt.Println(\"hi there\")\n```", "now"}, errPos: -1},
+		{name: "unicode", cmd: `say "héllo wörld" ✅`, expected: []string{"say", "héllo wörld", "✅"}, errPos: -1},
+		{name: "unterminated double quote", cmd: `say "hello`, errPos: 4},
+		{name: "unterminated single quote", cmd: `say a 'hello`, errPos: 6},
+		{name: "unterminated inline code", cmd: "say `hello", errPos: 4},
+		{name: "unterminated code block", cmd: "say ```hello`", errPos: 4},
+		{name: "trailing backslash", cmd: `say hello\`, errPos: 9},
+	}
+
+	for _, c := range testCases {
+		toks, err := ShellParser{}.Parse(c.cmd)
+		if c.errPos == -1 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			if !strSliceEqual(c.expected, toks, false) {
+				t.Errorf("%s: expected tokens %q, got %q", c.name, c.expected, toks)
+			}
+			continue
+		}
+
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: expected *ParseError, got %v", c.name, err)
+			continue
+		}
+		if parseErr.Pos != c.errPos {
+			t.Errorf("%s: expected error at position %d, got %d", c.name, c.errPos, parseErr.Pos)
+		}
+	}
+}
+
+func TestRouter_ParseWith(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses).ParseWith(ShellParser{})
+		args   []string
+		err    error
+	)
+
+	router.Has(NewRoute(&testPref{}).On("tag").Do(&testCmd{
+		HandleCallback: func(ctx context.Context) error {
+			args = utils.GetArgs(ctx)
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			err = utils.GetErr(ctx)
+		},
+		ParseCallback: func(cmd string) ([]string, error) {
+			t.Error("route parser should take priority over the handler parser")
+			return nil, nil
+		},
+	}).ParseWith(ShellParser{}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+`tag "hello world"`))
+	if expected := []string{"hello world"}; !strSliceEqual(expected, args, false) {
+		t.Errorf("expected args %q, got %q", expected, args)
+	}
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+`tag "hello world`))
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("expected a *ParseError to be resolved, got %v", err)
+	}
+}
+
+// testRecorder records the routes that handled or resolved a parse error.
+// It does not implement CmdParser, so routes using it share the parser of the Router.
+type testRecorder struct {
+	name  string
+	mu    *sync.Mutex // routes handling the same message run concurrently
+	fired *[]string
+}
+
+func (r *testRecorder) Handle(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.fired = append(*r.fired, r.name)
+	return nil
+}
+
+func (r *testRecorder) Resolve(ctx context.Context) {
+	if _, ok := utils.GetErr(ctx).(*ParseError); ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		*r.fired = append(*r.fired, r.name+" parse error")
+	}
+}
+
+func TestRouter_ParseWith_matched(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses).ParseWith(ShellParser{})
+		mu     sync.Mutex
+		fired  []string
+	)
+
+	record := func(name string) *testRecorder { return &testRecorder{name: name, mu: &mu, fired: &fired} }
+
+	router.Has(NewRoute(&testPref{}).On("echo").Do(record("echo")))
+	router.Has(NewRoute(&testPref{}).On("ban").Do(record("ban")))
+	router.Has(NewRoute(&testPref{}).On("kick").Do(record("kick")))
+	router.Has(NewRoute(&testPref{}).Do(record("default")))
+
+	testCases := []struct {
+		content  string
+		expected []string
+	}{
+		{content: testDefaultPrefix + `echo "hi"`, expected: []string{"echo", "default"}},
+		{content: testDefaultPrefix + `echo "hi`, expected: []string{"echo parse error"}},
+		{content: testDefaultPrefix + `ban "user`, expected: []string{"ban parse error"}},
+		{content: testDefaultPrefix + `'sup everyone`},
+	}
+
+	for _, c := range testCases {
+		fired = nil
+		router.onMessageCreate(ses, makeMockMsg(c.content))
+		if !strSliceEqual(c.expected, fired, true) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", c.content, c.expected, fired)
+		}
+	}
+}
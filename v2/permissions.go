@@ -0,0 +1,173 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+// permissionNames names each permission bit, in order of bit offset.
+var permissionNames = []struct {
+	bit  int
+	name string
+}{
+	{discordgo.PermissionCreateInstantInvite, "Create Instant Invite"},
+	{discordgo.PermissionKickMembers, "Kick Members"},
+	{discordgo.PermissionBanMembers, "Ban Members"},
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageServer, "Manage Server"},
+	{discordgo.PermissionAddReactions, "Add Reactions"},
+	{discordgo.PermissionViewAuditLogs, "View Audit Log"},
+	{discordgo.PermissionVoicePrioritySpeaker, "Priority Speaker"},
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionSendTTSMessages, "Send TTS Messages"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+	{discordgo.PermissionMentionEveryone, "Mention Everyone"},
+	{discordgo.PermissionUseExternalEmojis, "Use External Emojis"},
+	{discordgo.PermissionVoiceConnect, "Connect"},
+	{discordgo.PermissionVoiceSpeak, "Speak"},
+	{discordgo.PermissionVoiceMuteMembers, "Mute Members"},
+	{discordgo.PermissionVoiceDeafenMembers, "Deafen Members"},
+	{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+	{discordgo.PermissionVoiceUseVAD, "Use Voice Activity"},
+	{discordgo.PermissionChangeNickname, "Change Nickname"},
+	{discordgo.PermissionManageNicknames, "Manage Nicknames"},
+	{discordgo.PermissionManageRoles, "Manage Roles"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionManageEmojis, "Manage Emojis"},
+}
+
+// Permissions declares the Discord permissions required to invoke a Route. See Route.Require.
+type Permissions struct {
+	// User is a bitmask of permissions the author of the message needs in the channel,
+	// such as discordgo.PermissionManageMessages|discordgo.PermissionKickMembers.
+	User int
+	// Bot is a bitmask of permissions the bot needs in the channel.
+	Bot int
+	// AllowDM permits the route in direct messages, where there are no permissions to check.
+	// Otherwise, invoking the route in a direct message enters Resolve with a *PermissionError.
+	AllowDM bool
+}
+
+// PermissionError is returned when a Route is invoked without the Permissions it requires.
+type PermissionError struct {
+	Bot     bool // true if the bot is missing permissions, false if the author of the message is
+	Missing int  // bitmask of the missing permissions, zero if DM is true
+	DM      bool // true if the route was invoked in a direct message and does not allow it
+}
+
+// Names returns the names of the missing permissions, such as "Manage Messages".
+func (e *PermissionError) Names() []string {
+	return PermissionNames(e.Missing)
+}
+
+func (e *PermissionError) Error() string {
+	switch {
+	case e.DM:
+		return "command cannot be used in direct messages"
+	case e.Bot:
+		return "bot is missing permissions: " + strings.Join(e.Names(), ", ")
+	default:
+		return "missing permissions: " + strings.Join(e.Names(), ", ")
+	}
+}
+
+// PermissionNames returns the names of the permissions in a bitmask, in order of bit offset.
+// Unknown bits are named by their hexadecimal value.
+func PermissionNames(perms int) []string {
+	var names []string
+	for _, p := range permissionNames {
+		if perms&p.bit != 0 {
+			names = append(names, p.name)
+			perms &^= p.bit
+		}
+	}
+	for bit := 1; perms != 0 && bit > 0; bit <<= 1 {
+		if perms&bit != 0 {
+			names = append(names, fmt.Sprintf("0x%x", bit))
+			perms &^= bit
+		}
+	}
+	return names
+}
+
+// requiredPermissions merges the Permissions required by each route in path.
+// Requirements of routes preceding an isolated route are excluded.
+//
+// Returns false if no route declares Permissions.
+func requiredPermissions(path []*Route) (Permissions, bool) {
+	var (
+		required = Permissions{AllowDM: true}
+		declared bool
+	)
+
+	for _, r := range path[isolatedFrom(path):] {
+		if r.perms == nil {
+			continue
+		}
+		declared = true
+		required.User |= r.perms.User
+		required.Bot |= r.perms.Bot
+		required.AllowDM = required.AllowDM && r.perms.AllowDM
+	}
+
+	return required, declared
+}
+
+// checkPermissions returns a *PermissionError if the author of the message or the bot
+// does not have the required permissions in the channel of the message.
+//
+// ctx must contain the session and message.
+func checkPermissions(ctx context.Context, required Permissions) error {
+	var (
+		ses = utils.GetSes(ctx)
+		msg = utils.GetMsg(ctx)
+	)
+
+	if msg.GuildID == "" {
+		if required.AllowDM {
+			return nil
+		}
+		return &PermissionError{DM: true}
+	}
+
+	if required.User != 0 {
+		perms, err := channelPermissions(ses, msg.Author.ID, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+		if missing := required.User &^ perms; missing != 0 {
+			return &PermissionError{Missing: missing}
+		}
+	}
+
+	if required.Bot != 0 {
+		perms, err := channelPermissions(ses, ses.State.User.ID, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+		if missing := required.Bot &^ perms; missing != 0 {
+			return &PermissionError{Bot: true, Missing: missing}
+		}
+	}
+
+	return nil
+}
+
+// channelPermissions returns the permissions of a user in a guild channel.
+// If the guild, channel or member is not cached in State, they are requested from Discord.
+func channelPermissions(s *discordgo.Session, userID, channelID string) (int, error) {
+	perms, err := s.State.UserChannelPermissions(userID, channelID)
+	if err == discordgo.ErrStateNotFound {
+		return s.UserChannelPermissions(userID, channelID)
+	}
+	return perms, err
+}
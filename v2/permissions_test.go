@@ -0,0 +1,146 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+// makeMockGuildSes returns a session with a cached guild where the bot can manage messages and the author cannot.
+func makeMockGuildSes(t *testing.T) *discordgo.Session {
+	ses := makeMockSes()
+	err := ses.State.GuildAdd(&discordgo.Guild{
+		ID:      "guild_id_1",
+		OwnerID: "owner_id_1",
+		Roles: []*discordgo.Role{
+			{ID: "guild_id_1", Permissions: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+			{ID: "role_id_mod", Permissions: discordgo.PermissionManageMessages},
+		},
+		Members: []*discordgo.Member{
+			{GuildID: "guild_id_1", User: &discordgo.User{ID: "author_id_1"}},
+			{GuildID: "guild_id_1", User: &discordgo.User{ID: "self_id_1"}, Roles: []string{"role_id_mod"}},
+		},
+		Channels: []*discordgo.Channel{
+			{ID: "channel_id_1", GuildID: "guild_id_1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ses
+}
+
+func TestRoute_Require(t *testing.T) {
+	var (
+		ses      = makeMockGuildSes(t)
+		router   = New(ses)
+		handled  []string
+		resolved error
+	)
+
+	cmd := func(name string) *testCmd {
+		return &testCmd{
+			HandleCallback: func(_ context.Context) error {
+				handled = append(handled, name)
+				return nil
+			},
+			ResolveCallback: func(ctx context.Context) {
+				resolved = utils.GetErr(ctx)
+			},
+		}
+	}
+
+	middleware := &testMiddleware{name: "middleware", trace: &handled}
+
+	router.Has(NewRoute(&testPref{}).On("purge").Do(cmd("purge")).Use(middleware).
+		Require(Permissions{User: discordgo.PermissionManageMessages | discordgo.PermissionBanMembers}))
+	router.Has(NewRoute(&testPref{}).On("clean").Do(cmd("clean")).
+		Require(Permissions{Bot: discordgo.PermissionManageMessages}).
+		Has(
+			NewSubroute().On("all").Do(cmd("clean all")).
+				Require(Permissions{Bot: discordgo.PermissionKickMembers}),
+			NewSubroute().On("public").Do(cmd("clean public")).Isolate(),
+		))
+	router.Has(NewRoute(&testPref{}).On("ping").Do(cmd("ping")).Require(Permissions{AllowDM: true}))
+
+	tests := []struct {
+		content  string
+		dm       bool
+		handled  []string
+		resolved error
+	}{
+		{
+			content:  "purge",
+			resolved: &PermissionError{Missing: discordgo.PermissionManageMessages | discordgo.PermissionBanMembers},
+		},
+		{
+			content: "clean",
+			handled: []string{"clean"},
+		},
+		{
+			content:  "clean all",
+			resolved: &PermissionError{Bot: true, Missing: discordgo.PermissionKickMembers},
+		},
+		{
+			content: "clean public",
+			dm:      true,
+			handled: []string{"clean public"},
+		},
+		{
+			content:  "clean",
+			dm:       true,
+			resolved: &PermissionError{DM: true},
+		},
+		{
+			content: "ping",
+			dm:      true,
+			handled: []string{"ping"},
+		},
+	}
+
+	for _, tt := range tests {
+		handled, resolved = nil, nil
+
+		m := makeMockMsg(testDefaultPrefix + tt.content)
+		m.ChannelID = "channel_id_1"
+		if tt.dm {
+			m.GuildID = ""
+		}
+		router.onMessageCreate(ses, m)
+
+		if !strSliceEqual(tt.handled, handled, false) {
+			t.Errorf("%q: expected %v to run, got %v", tt.content, tt.handled, handled)
+		}
+		if tt.resolved == nil {
+			if resolved != nil {
+				t.Errorf("%q: expected no error, got %v", tt.content, resolved)
+			}
+			continue
+		}
+
+		var permErr *PermissionError
+		if !errors.As(resolved, &permErr) {
+			t.Errorf("%q: expected a PermissionError, got %v", tt.content, resolved)
+			continue
+		}
+		if *permErr != *tt.resolved.(*PermissionError) {
+			t.Errorf("%q: expected %+v, got %+v", tt.content, tt.resolved, permErr)
+		}
+	}
+}
+
+func TestPermissionNames(t *testing.T) {
+	names := PermissionNames(discordgo.PermissionBanMembers | discordgo.PermissionManageMessages | 1<<9)
+	expected := []string{"Ban Members", "Manage Messages", "0x200"}
+	if !strSliceEqual(expected, names, false) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	err := &PermissionError{Missing: discordgo.PermissionManageMessages | discordgo.PermissionBanMembers}
+	if err.Error() != "missing permissions: Ban Members, Manage Messages" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}
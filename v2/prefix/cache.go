@@ -0,0 +1,107 @@
+package prefix
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	prefix  string
+	ok      bool
+	expires time.Time
+}
+
+// Cache is a Store that caches the prefixes of another Store for a TTL, including guilds with no custom prefix.
+//
+// Changes made through the Cache are written to the underlying Store and invalidate the cached prefix.
+// Call Invalidate if the underlying Store is changed by other means.
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	nextSweep time.Time
+	gen       uint64 // incremented on invalidation, so a Get racing a change does not cache the old prefix
+}
+
+// NewCache returns a Cache of store, keeping prefixes for ttl.
+func NewCache(store Store, ttl time.Duration) *Cache {
+	return &Cache{
+		store:   store,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// Get returns the cached prefix of a guild, loading it from the underlying Store if it is not cached or expired.
+// Errors are not cached.
+func (c *Cache) Get(guildID string) (string, bool, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	e, cached := c.entries[guildID]
+	gen := c.gen
+	c.mu.Unlock()
+	if cached && now.Before(e.expires) {
+		return e.prefix, e.ok, nil
+	}
+
+	prefix, ok, err := c.store.Get(guildID)
+	if err != nil {
+		return "", false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.sweep(now)
+		c.entries[guildID] = cacheEntry{prefix: prefix, ok: ok, expires: now.Add(c.ttl)}
+	}
+	return prefix, ok, nil
+}
+
+// Set sets the prefix of a guild in the underlying Store.
+func (c *Cache) Set(guildID, prefix string) error {
+	defer c.Invalidate(guildID)
+	return c.store.Set(guildID, prefix)
+}
+
+// Delete removes the prefix of a guild from the underlying Store.
+func (c *Cache) Delete(guildID string) error {
+	defer c.Invalidate(guildID)
+	return c.store.Delete(guildID)
+}
+
+// Invalidate discards the cached prefix of a guild, so it is loaded from the underlying Store on the next Get.
+func (c *Cache) Invalidate(guildID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, guildID)
+	c.gen++
+}
+
+// InvalidateAll discards all cached prefixes.
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]cacheEntry{}
+	c.gen++
+}
+
+// sweep periodically discards expired entries, so guilds the bot has left are eventually forgotten.
+//
+// c.mu must be held.
+func (c *Cache) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	for guildID, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, guildID)
+		}
+	}
+	c.nextSweep = now.Add(c.ttl)
+}
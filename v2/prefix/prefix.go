@@ -0,0 +1,186 @@
+// Package prefix provides a Prefixer backed by a Store of custom guild prefixes, along with commands to manage them.
+package prefix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	sayori "github.com/pixeltopic/sayori/v2"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MaxLength is the maximum number of characters in a prefix set with the prefix command.
+const MaxLength = 16
+
+var (
+	// ErrEmpty is returned by the prefix set command when the prefix is empty.
+	ErrEmpty = errors.New("prefix cannot be empty")
+	// ErrTooLong is returned by the prefix set command when the prefix has more than MaxLength characters.
+	ErrTooLong = fmt.Errorf("prefix cannot be longer than %d characters", MaxLength)
+	// ErrInvalid is returned by the prefix set command when the prefix contains backticks or mentions,
+	// which would break out of the code span the prefix is shown in.
+	ErrInvalid = errors.New("prefix cannot contain backticks or mentions")
+)
+
+// mentionSyntax is the syntax of user, role, channel and mass mentions.
+var mentionSyntax = []string{"<@", "<#", "@everyone", "@here"}
+
+// Prefixer is a Prefixer loading custom guild prefixes from a Store. Guilds with no custom prefix use the default prefix.
+//
+// Loading a prefix hits the Store on every message, so wrap slow stores in a Cache.
+type Prefixer struct {
+	store Store
+	def   string
+}
+
+// New returns a Prefixer loading prefixes from store, with def as the default prefix.
+func New(store Store, def string) *Prefixer {
+	return &Prefixer{store: store, def: def}
+}
+
+// Load returns the custom prefix of a guild. Direct messages and guilds whose prefix cannot be loaded have no custom prefix.
+func (p *Prefixer) Load(guildID string) (string, bool) {
+	if guildID == "" {
+		return "", false
+	}
+	prefix, ok, err := p.store.Get(guildID)
+	if err != nil {
+		return "", false
+	}
+	return prefix, ok
+}
+
+// Default returns the default prefix.
+func (p *Prefixer) Default() string {
+	return p.def
+}
+
+// Store returns the Store of the Prefixer.
+func (p *Prefixer) Store() Store {
+	return p.store
+}
+
+// Route returns a "prefix" route using the Prefixer, with the following subcommands:
+//
+// "prefix" or "prefix show" shows the prefix of the current guild.
+//
+// "prefix set <prefix>" sets the prefix of the current guild. The route is parsed with ShellParser,
+// so the prefix can be quoted to include whitespace, such as "prefix set 'bot '".
+// Prefixes containing backticks or mentions are rejected with ErrInvalid.
+//
+// "prefix reset" restores the default prefix of the current guild.
+//
+// Setting and resetting the prefix is limited to members with the Manage Server permission, and cannot be done in DMs.
+func (p *Prefixer) Route() *sayori.Route {
+	manage := sayori.Permissions{User: discordgo.PermissionManageServer}
+
+	return sayori.NewRoute(p).On("prefix").Do(&showCmd{p: p}).ParseWith(sayori.ShellParser{}).
+		Meta(sayori.Meta{Description: "Shows or changes the prefix of this server", Category: "Settings"}).
+		Has(
+			sayori.NewSubroute().On("show").Do(&showCmd{p: p}).
+				Meta(sayori.Meta{Description: "Shows the prefix of this server"}),
+			sayori.NewSubroute().On("set").Do(&setCmd{p: p}).
+				Args(sayori.Arg{Name: "prefix"}).
+				Require(manage).
+				Meta(sayori.Meta{Description: "Changes the prefix of this server", Examples: []string{"prefix set ?"}}),
+			sayori.NewSubroute().On("reset").Do(&resetCmd{p: p}).
+				Require(manage).
+				Meta(sayori.Meta{Description: "Restores the default prefix of this server"}),
+		)
+}
+
+type (
+	showCmd  struct{ p *Prefixer }
+	setCmd   struct{ p *Prefixer }
+	resetCmd struct{ p *Prefixer }
+)
+
+// Handle shows the prefix of the current guild.
+func (c *showCmd) Handle(ctx context.Context) error {
+	cmd := sayori.CmdFromContext(ctx)
+
+	prefix, ok := c.p.Load(cmd.Msg.GuildID)
+	if !ok {
+		prefix = c.p.Default()
+	}
+	return reply(cmd, fmt.Sprintf("The prefix is `%s`", prefix))
+}
+
+// Resolve sends any error to the channel.
+func (c *showCmd) Resolve(ctx context.Context) {
+	resolve(ctx)
+}
+
+// Handle sets the prefix of the current guild.
+func (c *setCmd) Handle(ctx context.Context) error {
+	cmd := sayori.CmdFromContext(ctx)
+
+	prefix := cmd.StringArg("prefix")
+	if err := validate(prefix); err != nil {
+		return err
+	}
+
+	if err := c.p.store.Set(cmd.Msg.GuildID, prefix); err != nil {
+		return err
+	}
+	return reply(cmd, fmt.Sprintf("The prefix is now `%s`", prefix))
+}
+
+// validate returns an error if prefix cannot be set with the prefix command.
+func validate(prefix string) error {
+	switch {
+	case strings.TrimSpace(prefix) == "":
+		return ErrEmpty
+	case utf8.RuneCountInString(prefix) > MaxLength:
+		return ErrTooLong
+	case strings.ContainsRune(prefix, '`'):
+		return ErrInvalid
+	}
+	for _, mention := range mentionSyntax {
+		if strings.Contains(prefix, mention) {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// Resolve sends any error to the channel.
+func (c *setCmd) Resolve(ctx context.Context) {
+	resolve(ctx)
+}
+
+// Handle restores the default prefix of the current guild.
+func (c *resetCmd) Handle(ctx context.Context) error {
+	cmd := sayori.CmdFromContext(ctx)
+
+	if err := c.p.store.Delete(cmd.Msg.GuildID); err != nil {
+		return err
+	}
+	return reply(cmd, fmt.Sprintf("The prefix is now `%s`", c.p.Default()))
+}
+
+// Resolve sends any error to the channel.
+func (c *resetCmd) Resolve(ctx context.Context) {
+	resolve(ctx)
+}
+
+// reply sends content without allowing it to mention anyone, since it may contain a prefix set by a store.
+func reply(cmd *sayori.CmdContext, content string) error {
+	_, err := cmd.Ses.ChannelMessageSendComplex(cmd.Msg.ChannelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+func resolve(ctx context.Context) {
+	cmd := sayori.CmdFromContext(ctx)
+
+	if cmd.Err != nil {
+		_, _ = cmd.Ses.ChannelMessageSend(cmd.Msg.ChannelID, cmd.Err.Error())
+	}
+}
@@ -0,0 +1,151 @@
+package prefix
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCountingStore counts calls to Get of the underlying Store.
+type testCountingStore struct {
+	Store
+	gets int
+	err  error
+}
+
+func (s *testCountingStore) Get(guildID string) (string, bool, error) {
+	s.gets++
+	if s.err != nil {
+		return "", false, s.err
+	}
+	return s.Store.Get(guildID)
+}
+
+func testStore(t *testing.T, s Store) {
+	if _, ok, err := s.Get("guild_id_1"); ok || err != nil {
+		t.Fatalf("expected no prefix, got ok=%v err=%v", ok, err)
+	}
+	if err := s.Set("guild_id_1", "?"); err != nil {
+		t.Fatal(err)
+	}
+	if prefix, ok, _ := s.Get("guild_id_1"); !ok || prefix != "?" {
+		t.Errorf("expected prefix '?', got %q (ok=%v)", prefix, ok)
+	}
+	if err := s.Delete("guild_id_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get("guild_id_1"); ok {
+		t.Error("expected prefix to be deleted")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prefix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prefixes.json")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	if err := s.Set("guild_id_2", "bot "); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix, ok, _ := reloaded.Get("guild_id_2"); !ok || prefix != "bot " {
+		t.Errorf("expected prefix to persist, got %q (ok=%v)", prefix, ok)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var (
+		now     = time.Unix(1600000000, 0)
+		counter = &testCountingStore{Store: NewMemoryStore()}
+		cache   = NewCache(counter, time.Minute)
+		p       = New(cache, "!")
+	)
+	cache.now = func() time.Time { return now }
+
+	_ = counter.Store.Set("guild_id_1", "?")
+
+	for i := 0; i < 3; i++ {
+		if prefix, ok := p.Load("guild_id_1"); !ok || prefix != "?" {
+			t.Fatalf("expected prefix '?', got %q (ok=%v)", prefix, ok)
+		}
+	}
+	if counter.gets != 1 {
+		t.Errorf("expected prefix to be cached, got %d loads", counter.gets)
+	}
+
+	// guilds without a custom prefix are cached too
+	p.Load("guild_id_2")
+	p.Load("guild_id_2")
+	if counter.gets != 2 {
+		t.Errorf("expected missing prefix to be cached, got %d loads", counter.gets)
+	}
+
+	_ = cache.Set("guild_id_1", "$")
+	if prefix, _ := p.Load("guild_id_1"); prefix != "$" {
+		t.Errorf("expected Set to invalidate the cache, got %q", prefix)
+	}
+
+	_ = counter.Store.Set("guild_id_1", "%")
+	if prefix, _ := p.Load("guild_id_1"); prefix != "$" {
+		t.Errorf("expected cached prefix before the TTL, got %q", prefix)
+	}
+	now = now.Add(time.Minute)
+	if prefix, _ := p.Load("guild_id_1"); prefix != "%" {
+		t.Errorf("expected prefix to be reloaded after the TTL, got %q", prefix)
+	}
+
+	_ = counter.Store.Set("guild_id_1", "&")
+	cache.Invalidate("guild_id_1")
+	if prefix, _ := p.Load("guild_id_1"); prefix != "&" {
+		t.Errorf("expected Invalidate to discard the cached prefix, got %q", prefix)
+	}
+
+	counter.err = errors.New("unavailable")
+	cache.InvalidateAll()
+	if _, ok := p.Load("guild_id_1"); ok {
+		t.Error("expected a failing store to fall back to the default prefix")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		expected error
+	}{
+		{prefix: "?"},
+		{prefix: "bot "},
+		{prefix: "don't "},
+		{prefix: " ", expected: ErrEmpty},
+		{prefix: "a-very-long-prefix", expected: ErrTooLong},
+		{prefix: "`", expected: ErrInvalid},
+		{prefix: "`@everyone`", expected: ErrInvalid},
+		{prefix: "@here", expected: ErrInvalid},
+		{prefix: "<@&123>", expected: ErrInvalid},
+		{prefix: "<#123>", expected: ErrInvalid},
+	}
+
+	for _, c := range testCases {
+		if err := validate(c.prefix); err != c.expected {
+			t.Errorf("%q: expected %v, got %v", c.prefix, c.expected, err)
+		}
+	}
+}
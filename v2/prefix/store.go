@@ -0,0 +1,153 @@
+package prefix
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps the custom prefix of each guild.
+type Store interface {
+	// Get returns the prefix of a guild, and false if the guild has no custom prefix.
+	Get(guildID string) (string, bool, error)
+	// Set sets the prefix of a guild.
+	Set(guildID, prefix string) error
+	// Delete removes the prefix of a guild. It no-ops if the guild has no custom prefix.
+	Delete(guildID string) error
+}
+
+// MemoryStore is a Store that keeps prefixes in memory.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	prefixes map[string]string
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{prefixes: map[string]string{}}
+}
+
+// Get returns the prefix of a guild.
+func (s *MemoryStore) Get(guildID string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix, ok := s.prefixes[guildID]
+	return prefix, ok, nil
+}
+
+// Set sets the prefix of a guild.
+func (s *MemoryStore) Set(guildID, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prefixes[guildID] = prefix
+	return nil
+}
+
+// Delete removes the prefix of a guild.
+func (s *MemoryStore) Delete(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.prefixes, guildID)
+	return nil
+}
+
+// FileStore is a Store that keeps prefixes in memory and persists them to a JSON file, mapping guild IDs to prefixes.
+//
+// The file is rewritten on every change, so FileStore is suited to bots in a modest number of guilds.
+type FileStore struct {
+	mu       sync.RWMutex
+	path     string
+	prefixes map[string]string
+}
+
+// NewFileStore returns a FileStore persisting to path, loading the prefixes already in it.
+// The file is created on the first change if it does not exist.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, prefixes: map[string]string{}}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.prefixes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the prefix of a guild.
+func (s *FileStore) Get(guildID string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix, ok := s.prefixes[guildID]
+	return prefix, ok, nil
+}
+
+// Set sets the prefix of a guild and persists it. The prefix is unchanged if it cannot be persisted.
+func (s *FileStore) Set(guildID, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, existed := s.prefixes[guildID]
+	s.prefixes[guildID] = prefix
+	if err := s.save(); err != nil {
+		if existed {
+			s.prefixes[guildID] = old
+		} else {
+			delete(s.prefixes, guildID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the prefix of a guild and persists the change. The prefix is kept if the change cannot be persisted.
+func (s *FileStore) Delete(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, existed := s.prefixes[guildID]
+	if !existed {
+		return nil
+	}
+	delete(s.prefixes, guildID)
+	if err := s.save(); err != nil {
+		s.prefixes[guildID] = old
+		return err
+	}
+	return nil
+}
+
+// save writes the prefixes to a temporary file and renames it over the file, so the file is never partially written.
+//
+// s.mu must be held.
+func (s *FileStore) save() error {
+	b, err := json.MarshalIndent(s.prefixes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,282 @@
+package v2
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ReactionEvent is a set of reaction events a ReactionRoute handles, combined with a bitwise "or".
+type ReactionEvent int
+
+const (
+	// ReactionAdd handles DiscordGo MessageReactionAdd events.
+	ReactionAdd ReactionEvent = 1 << iota
+	// ReactionRemove handles DiscordGo MessageReactionRemove events.
+	ReactionRemove
+)
+
+// ReactionRoute represents a handler which consumes DiscordGo MessageReactionAdd or MessageReactionRemove events
+// of an emoji, such as ✅ to approve a request.
+//
+// The reaction is attached to the Context of the Handler, and can be read with utils.GetReaction
+// and utils.IsReactionRemoved. Reactions have no Message attached; utils.GetMsg returns nil.
+//
+// Reactions added or removed by the bot itself are ignored. Other reactions can be filtered with middlewares,
+// such as an acl.ACL or a filter.Guard of a predicate reading utils.GetReaction.
+// Middlewares added with Router.Use do not run for reactions; add them with Router.UseReaction instead.
+type ReactionRoute struct {
+	h           Handler
+	emoji       string
+	messageID   string
+	channelID   string
+	events      ReactionEvent
+	middlewares []Wrapper
+}
+
+// reactionRoot is a ReactionRoute that was added to the Router via HasReaction or HasReactionOnce.
+type reactionRoot struct {
+	route *ReactionRoute
+	once  bool
+	fired uint32
+}
+
+// NewReactionRoute returns a new ReactionRoute handling added reactions of emoji.
+//
+// emoji is a unicode emoji such as "✅", or the ID or "name:id" of a custom emoji. If emoji is empty, every emoji is handled.
+func NewReactionRoute(emoji string) *ReactionRoute {
+	return &ReactionRoute{
+		emoji:       emoji,
+		events:      ReactionAdd,
+		middlewares: []Wrapper{},
+	}
+}
+
+// Message limits the route to reactions on the message with the given ID.
+func (r *ReactionRoute) Message(messageID string) *ReactionRoute {
+	r.messageID = messageID
+	return r
+}
+
+// Channel limits the route to reactions in the channel with the given ID.
+func (r *ReactionRoute) Channel(channelID string) *ReactionRoute {
+	r.channelID = channelID
+	return r
+}
+
+// Events sets the reaction events the route handles, such as ReactionAdd|ReactionRemove. The default is ReactionAdd.
+func (r *ReactionRoute) Events(events ReactionEvent) *ReactionRoute {
+	r.events = events
+	return r
+}
+
+// Use adds middlewares to the route, which run after the reaction middlewares of the Router.
+// Errors returned by middlewares skip Handle, and can be handled by Resolve.
+func (r *ReactionRoute) Use(middlewares ...Middlewarer) *ReactionRoute {
+	for _, m := range middlewares {
+		r.middlewares = append(r.middlewares, Adapt(m))
+	}
+	return r
+}
+
+// Wrap adds wrapping middlewares to the route. Wrap and Use share the same chain.
+func (r *ReactionRoute) Wrap(wrappers ...Wrapper) *ReactionRoute {
+	r.middlewares = append(r.middlewares, wrappers...)
+	return r
+}
+
+// Do execution of the provided Handler when a matching reaction is added or removed.
+// Handling errors will be handled by Resolve if Resolver is implemented, otherwise skipped.
+//
+// No-ops if Handler is nil.
+//
+// If Do is called multiple times, the previous Do call will be overwritten.
+func (r *ReactionRoute) Do(h Handler) *ReactionRoute {
+	r.h = h
+	return r
+}
+
+// matches reports if the route handles the reaction event.
+func (r *ReactionRoute) matches(reaction *discordgo.MessageReaction, event ReactionEvent) bool {
+	switch {
+	case r.events&event == 0:
+		return false
+	case r.messageID != "" && r.messageID != reaction.MessageID:
+		return false
+	case r.channelID != "" && r.channelID != reaction.ChannelID:
+		return false
+	}
+	return r.emoji == "" || matchesEmoji(r.emoji, reaction.Emoji)
+}
+
+// matchesEmoji reports if emoji is the unicode emoji, or the ID or "name:id" of the custom emoji e.
+func matchesEmoji(emoji string, e discordgo.Emoji) bool {
+	if e.ID == "" {
+		return emoji == e.Name
+	}
+	return emoji == e.ID || emoji == e.APIName()
+}
+
+// run executes the Handler of the route with the reaction middlewares of the Router and the middlewares of the route.
+func (r *ReactionRoute) run(ctx context.Context, wrappers []Wrapper) {
+	if r.h == nil {
+		return
+	}
+
+	middlewares := append(wrappers[:len(wrappers):len(wrappers)], r.middlewares...)
+	if err := chainMiddlewares(middlewares, r.h.Handle)(ctx); err != nil {
+		ctx = utils.WithErr(ctx, err)
+	}
+
+	handleResolve(r.h)(ctx)
+}
+
+// claim reports if the route may run. Routes added with HasReactionOnce can only be claimed once.
+func (root *reactionRoot) claim() bool {
+	if !root.once {
+		return true
+	}
+	return atomic.CompareAndSwapUint32(&root.fired, 0, 1)
+}
+
+// UseReaction adds middlewares that run for every reaction route bound to the Router, before the middlewares of the route.
+//
+// The Context holds the reaction instead of a message: utils.GetReaction returns the user, channel and guild of the
+// reaction, and utils.GetMsg returns nil. Middlewares such as acl.ACL support both, so a blacklist added with Use
+// should also be added with UseReaction to apply to reactions.
+func (r *Router) UseReaction(middlewares ...Middlewarer) *Router {
+	wrappers := make([]Wrapper, 0, len(middlewares))
+	for _, m := range middlewares {
+		wrappers = append(wrappers, Adapt(m))
+	}
+	return r.WrapReaction(wrappers...)
+}
+
+// WrapReaction adds wrapping middlewares that run for every reaction route bound to the Router.
+// WrapReaction and UseReaction share the same chain; the first middleware added is the outermost.
+func (r *Router) WrapReaction(wrappers ...Wrapper) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reactionWrappers = append(r.reactionWrappers[:len(r.reactionWrappers):len(r.reactionWrappers)], wrappers...)
+	r.idx = nil
+	return r
+}
+
+// HasReaction binds a ReactionRoute to the Router.
+//
+// It returns a function that will remove the route when executed.
+func (r *Router) HasReaction(route *ReactionRoute) func() {
+	return r.addReaction(route, false)
+}
+
+// HasReactionOnce binds a ReactionRoute to the Router, but the route will only fire at most once.
+//
+// It returns a function that will remove the route when executed.
+func (r *Router) HasReactionOnce(route *ReactionRoute) func() {
+	return r.addReaction(route, true)
+}
+
+// addReaction registers a copy of route and returns a function that will remove it.
+func (r *Router) addReaction(route *ReactionRoute, once bool) func() {
+	if route == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *route
+	copied.middlewares = append([]Wrapper{}, route.middlewares...)
+	root := &reactionRoot{route: &copied, once: once}
+	r.reactions = append(r.reactions[:len(r.reactions):len(r.reactions)], root)
+	r.idx = nil
+
+	r.ensureRegistered()
+
+	return func() { r.removeReaction(root) }
+}
+
+// removeReaction unbinds a ReactionRoute from the Router. No-ops if it was already removed.
+func (r *Router) removeReaction(root *reactionRoot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, candidate := range r.reactions {
+		if candidate == root {
+			r.reactions = append(r.reactions[:i:i], r.reactions[i+1:]...)
+			r.idx = nil
+			return
+		}
+	}
+}
+
+// onMessageReactionAdd is the DiscordGo MessageReactionAdd handler shared by all reaction routes bound to the Router.
+func (r *Router) onMessageReactionAdd(s *discordgo.Session, m *discordgo.MessageReactionAdd) {
+	r.dispatchReaction(s, m.MessageReaction, ReactionAdd)
+}
+
+// onMessageReactionRemove is the DiscordGo MessageReactionRemove handler shared by all reaction routes bound to the Router.
+func (r *Router) onMessageReactionRemove(s *discordgo.Session, m *discordgo.MessageReactionRemove) {
+	r.dispatchReaction(s, m.MessageReaction, ReactionRemove)
+}
+
+// dispatchReaction passes a reaction event to waiters, then runs the reaction routes handling it in order of which they were added.
+func (r *Router) dispatchReaction(s *discordgo.Session, reaction *discordgo.MessageReaction, event ReactionEvent) {
+	if reaction == nil {
+		return
+	}
+	if s != nil && s.State != nil && s.State.User != nil && reaction.UserID == s.State.User.ID {
+		return
+	}
+
+	var (
+		idx = r.index()
+		ctx = utils.WithReaction(withRouter(utils.WithSes(r.baseContext(), s), r), reaction, event == ReactionRemove)
+	)
+	r.notifyWaiters(ctx)
+
+	var roots []*reactionRoot
+	for _, root := range idx.reactions {
+		if root.route.matches(reaction, event) {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) == 0 {
+		return
+	}
+	done, ok := r.begin()
+	if !ok {
+		return
+	}
+	if idx.blocksEvents(s) {
+		ctx = withBlocking(ctx)
+	}
+
+	concurrent := len(roots) > 1 && idx.concurrent(s)
+	idx.execute(ctx, done, func() {
+		var wg sync.WaitGroup
+		for _, root := range roots {
+			if !root.claim() {
+				continue
+			}
+			if root.once {
+				r.removeReaction(root)
+			}
+			if !concurrent {
+				root.route.run(ctx, idx.reactionWrappers)
+				continue
+			}
+			wg.Add(1)
+			go func(route *ReactionRoute) {
+				defer wg.Done()
+				route.run(ctx, idx.reactionWrappers)
+			}(root.route)
+		}
+		wg.Wait()
+	})
+}
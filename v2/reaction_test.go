@@ -0,0 +1,96 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pixeltopic/sayori/v2/acl"
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func makeMockReaction(userID, messageID string, emoji discordgo.Emoji) *discordgo.MessageReaction {
+	return &discordgo.MessageReaction{
+		UserID:    userID,
+		MessageID: messageID,
+		ChannelID: "channel_id_1",
+		GuildID:   "guild_id_1",
+		Emoji:     emoji,
+	}
+}
+
+func TestRouter_HasReaction(t *testing.T) {
+	var (
+		ses      = makeMockSes()
+		router   = New(ses)
+		fired    []string
+		resolved []error
+		approve  = discordgo.Emoji{Name: "✅"}
+		custom   = discordgo.Emoji{Name: "approve", ID: "emoji_id_1"}
+	)
+
+	record := func(name string) *testCmd {
+		return &testCmd{
+			HandleCallback: func(ctx context.Context) error {
+				event := "add"
+				if utils.IsReactionRemoved(ctx) {
+					event = "remove"
+				}
+				fired = append(fired, name+":"+event+":"+utils.GetReaction(ctx).MessageID)
+				return nil
+			},
+			ResolveCallback: func(ctx context.Context) {
+				resolved = append(resolved, utils.GetErr(ctx))
+			},
+		}
+	}
+
+	// a message middleware dereferencing the message must not run for reactions
+	router.Wrap(WrapperFunc(func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context) error {
+			if CmdFromContext(ctx).Msg.Author.ID == "banned_id_1" {
+				return errors.New("banned")
+			}
+			return next(ctx)
+		}
+	}))
+
+	blacklist := acl.NewMemoryStore()
+	_ = blacklist.Add("blacklist", "", acl.Rule{Kind: acl.KindUser, ID: "banned_id_1", Effect: acl.Deny})
+	router.UseReaction(acl.New("blacklist", blacklist))
+
+	router.HasReaction(NewReactionRoute("✅").Do(record("approve")))
+	router.HasReaction(NewReactionRoute("emoji_id_1").Message("message_id_2").Events(ReactionAdd | ReactionRemove).Do(record("custom")))
+	router.HasReactionOnce(NewReactionRoute("").Message("message_id_3").Do(record("once")))
+
+	testCases := []struct {
+		reaction *discordgo.MessageReaction
+		event    ReactionEvent
+		expected []string
+	}{
+		{reaction: makeMockReaction("author_id_1", "message_id_1", approve), event: ReactionAdd, expected: []string{"approve:add:message_id_1"}},
+		{reaction: makeMockReaction("author_id_1", "message_id_1", approve), event: ReactionRemove},
+		{reaction: makeMockReaction("self_id_1", "message_id_1", approve), event: ReactionAdd},
+		{reaction: makeMockReaction("author_id_1", "message_id_1", custom), event: ReactionAdd},
+		{reaction: makeMockReaction("author_id_1", "message_id_2", custom), event: ReactionRemove, expected: []string{"custom:remove:message_id_2"}},
+		{reaction: makeMockReaction("author_id_1", "message_id_3", custom), event: ReactionAdd, expected: []string{"once:add:message_id_3"}},
+		{reaction: makeMockReaction("author_id_1", "message_id_3", custom), event: ReactionAdd},
+	}
+
+	for i, c := range testCases {
+		fired = nil
+		router.dispatchReaction(ses, c.reaction, c.event)
+		if !strSliceEqual(c.expected, fired, false) {
+			t.Errorf("case %d: expected handlers %v to fire, got %v", i, c.expected, fired)
+		}
+	}
+
+	fired, resolved = nil, nil
+	router.onMessageReactionAdd(ses, &discordgo.MessageReactionAdd{MessageReaction: makeMockReaction("banned_id_1", "message_id_1", approve)})
+	var aclErr *acl.Error
+	if len(fired) != 0 || len(resolved) != 1 || !errors.As(resolved[0], &aclErr) {
+		t.Errorf("expected the blacklist to deny the reaction, got handlers %v and errors %v", fired, resolved)
+	}
+}
@@ -3,24 +3,24 @@ package v2
 import (
 	"context"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/pixeltopic/sayori/v2/utils"
+	"github.com/pixeltopic/sayori/v2/filter"
 )
 
 var cmdParserDefault = strings.Fields
 
-// handleParse checks if an event implements CmdParser; if it does, runs CmdParser. Else, runs default parser
-func handleParse(h Handler, content string) ([]string, error) {
-	var (
-		p  CmdParser
-		ok bool
-	)
-	if p, ok = h.(CmdParser); !ok {
-		return cmdParserDefault(content), nil
-	}
+// FilterPolicy determines what happens to a message rejected by the filter of a Route.
+type FilterPolicy int
 
-	return p.Parse(content)
-}
+const (
+	// FilterDrop silently ignores rejected messages.
+	FilterDrop FilterPolicy = iota
+	// FilterResolve enters Resolve with a *filter.Error containing the failed filters.
+	FilterResolve
+)
 
 // handleResolve returns the Resolve func if Handler implements Resolver. Otherwise returns a Resolve func stub.
 func handleResolve(h Handler) func(ctx context.Context) {
@@ -30,15 +30,48 @@ func handleResolve(h Handler) func(ctx context.Context) {
 	return func(_ context.Context) {}
 }
 
-// handleMiddlewares runs each middleware in order until completion.
-// Will abort on the first error returned by a middleware.
-func handleMiddlewares(ctx context.Context, m []Middlewarer) error {
-	for i := 0; i < len(m); i++ {
-		if err := m[i].Do(ctx); err != nil {
-			return err
+// chainMiddlewares wraps h with middlewares, where the first middleware is the outermost.
+func chainMiddlewares(middlewares []Wrapper, h HandleFunc) HandleFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i].Wrap(h)
+	}
+	return h
+}
+
+// inheritedMiddlewares returns the middlewares of each route in path, from the root route to the last route.
+// Middlewares of routes preceding an isolated route are excluded.
+func inheritedMiddlewares(path []*Route) []Wrapper {
+	var middlewares []Wrapper
+	for _, r := range path[isolatedFrom(path):] {
+		middlewares = append(middlewares, r.middlewares...)
+	}
+	return middlewares
+}
+
+// checkFilters validates the message against the filter of each route in path, from the root route to the last route.
+// Filters of routes preceding an isolated route are excluded.
+//
+// Returns whether the policy of the first filter rejecting the message is to drop it, along with its error.
+func checkFilters(ctx context.Context, path []*Route) (bool, error) {
+	for _, r := range path[isolatedFrom(path):] {
+		if r.filter == 0 {
+			continue
+		}
+		if ok, failed := r.filter.Validate(ctx); !ok {
+			return r.filterPolicy == FilterDrop, failed.AsError()
 		}
 	}
-	return nil
+	return false, nil
+}
+
+// isolatedFrom returns the index of the last isolated route in path, or 0 if there is none.
+func isolatedFrom(path []*Route) int {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].isolated {
+			return i
+		}
+	}
+	return 0
 }
 
 // trimPrefix accepts a command (with prefix attached) and attempts to return the command without the prefix.
@@ -68,11 +101,21 @@ func trimPrefix(command, prefix string) (string, bool) {
 //
 // Routes can be modified after they are added to the router, and are not goroutine safe.
 type Route struct {
-	h           Handler
-	p           Prefixer
-	aliases     []string
-	subroutes   []*Route
-	middlewares []Middlewarer
+	h            Handler
+	p            Prefixer
+	parser       CmdParser
+	args         []Arg
+	flags        []Flag
+	meta         Meta
+	perms        *Permissions
+	filter       filter.Filter
+	filterPolicy FilterPolicy
+	isolated     bool
+	edits        *time.Duration
+	confirm      *Confirm
+	aliases      []string
+	subroutes    []*Route
+	middlewares  []Wrapper
 }
 
 // IsDefault returns true if a route has no aliases assigned.
@@ -105,17 +148,31 @@ func copyRoute(r Route) Route {
 	aliasesCopy := make([]string, len(r.aliases))
 	subrouteCopy := make([]*Route, len(r.subroutes))
 	// acceptable to not recursively copy as subroutes can never be directly accessed outside of package
-	mwCopy := make([]Middlewarer, len(r.middlewares))
+	mwCopy := make([]Wrapper, len(r.middlewares))
+	argsCopy := make([]Arg, len(r.args))
+	flagsCopy := make([]Flag, len(r.flags))
 	copy(aliasesCopy, r.aliases)
+	copy(argsCopy, r.args)
+	copy(flagsCopy, r.flags)
 	copy(subrouteCopy, r.subroutes)
 	copy(mwCopy, r.middlewares)
 
 	return Route{
-		h:           r.h,
-		p:           r.p,
-		aliases:     aliasesCopy,
-		subroutes:   subrouteCopy,
-		middlewares: mwCopy,
+		h:            r.h,
+		p:            r.p,
+		parser:       r.parser,
+		args:         argsCopy,
+		flags:        flagsCopy,
+		meta:         r.meta,
+		perms:        r.perms,
+		filter:       r.filter,
+		filterPolicy: r.filterPolicy,
+		isolated:     r.isolated,
+		edits:        r.edits,
+		confirm:      r.confirm,
+		aliases:      aliasesCopy,
+		subroutes:    subrouteCopy,
+		middlewares:  mwCopy,
 	}
 }
 
@@ -155,16 +212,115 @@ func (r *Route) findAllSubroutes(subAlias string) (routes []*Route) {
 // getGuildPrefix returns guildID's custom prefix or if none,
 // returns default prefix
 func (r *Route) getGuildPrefix(guildID string) string {
-	if r.p == nil {
+	return guildPrefix(r.p, guildID)
+}
+
+// guildPrefix returns guildID's custom prefix from p or if none,
+// returns default prefix. A nil Prefixer has an empty prefix.
+func guildPrefix(p Prefixer, guildID string) string {
+	if p == nil {
 		return ""
 	}
-	prefix, ok := r.p.Load(guildID)
+	prefix, ok := p.Load(guildID)
 	if !ok {
-		prefix = r.p.Default()
+		prefix = p.Default()
 	}
 	return prefix
 }
 
+// prefixSet is the set of prefixes a Prefixer loaded for a guild.
+type prefixSet struct {
+	prefixes []string
+	opts     PrefixOptions
+}
+
+// loadPrefixes returns the prefixes of guildID. A nil Prefixer has a single empty prefix.
+//
+// If p implements MultiPrefixer, all of its prefixes are returned. Otherwise, the set only contains the guild prefix.
+func loadPrefixes(p Prefixer, guildID string) prefixSet {
+	mp, ok := p.(MultiPrefixer)
+	if !ok {
+		return prefixSet{prefixes: []string{guildPrefix(p, guildID)}}
+	}
+
+	prefixes, ok := mp.LoadAll(guildID)
+	if !ok {
+		prefixes = []string{mp.Default()}
+	}
+	return prefixSet{prefixes: prefixes, opts: mp.PrefixOptions()}
+}
+
+// trim accepts a command (with prefix attached) and attempts to return the command without the longest matching prefix.
+// Returns the prefix as written in the command along with the command.
+//
+// Like trimPrefix, an empty prefix matches any non-empty command, and a command consisting only of
+// the longest matching prefix does not match.
+func (ps prefixSet) trim(command string) (string, string, bool) {
+	var (
+		matched string
+		longest int // length of the longest matching prefix in runes
+		found   bool
+	)
+
+	for _, prefix := range ps.prefixes {
+		n, ok := matchPrefix(command, prefix, ps.opts.CaseInsensitive)
+		if !ok {
+			continue
+		}
+		if size := utf8.RuneCountInString(prefix); !found || size > longest {
+			matched, longest, found = command[:n], size, true
+		}
+	}
+	if !found {
+		return "", "", false
+	}
+
+	cmd := command[len(matched):]
+	if ps.opts.TrimSpace {
+		cmd = strings.TrimLeftFunc(cmd, unicode.IsSpace)
+	}
+	if cmd == "" {
+		return "", "", false
+	}
+	return matched, cmd, true
+}
+
+// matchPrefix reports if command starts with prefix, along with the length in bytes of the prefix as written in command.
+//
+// If fold is true, runes are compared under Unicode simple case folding,
+// so the prefix as written may have a different length in bytes than prefix.
+func matchPrefix(command, prefix string, fold bool) (int, bool) {
+	if !fold {
+		return len(prefix), strings.HasPrefix(command, prefix)
+	}
+
+	n := 0
+	for _, want := range prefix {
+		if n >= len(command) {
+			return 0, false
+		}
+		got, size := utf8.DecodeRuneInString(command[n:])
+		if !equalFoldRune(got, want) {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+// equalFoldRune reports if a and b are equal under Unicode simple case folding.
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
 // On adds new identifiers for a Route.
 // By default, aliases with whitespaces will not be matched unless a Commander also implements the CmdParser interface
 //
@@ -188,8 +344,110 @@ func (r *Route) Has(subroutes ...*Route) *Route {
 // Use adds middlewares to the route.
 // Middlewares are executed in order of which they were added, and will always run immediately before the core handler.
 // Middleware errors can be handled by Resolve.
+//
+// Middlewares are inherited by subroutes: when a subroute is invoked, the middlewares of the Router run first,
+// followed by the middlewares of each route from the root route down to the subroute. See Isolate to opt out.
 func (r *Route) Use(middlewares ...Middlewarer) *Route {
-	r.middlewares = append(r.middlewares, middlewares...)
+	for _, m := range middlewares {
+		r.middlewares = append(r.middlewares, Adapt(m))
+	}
+	return r
+}
+
+// Wrap adds wrapping middlewares to the route, which can run code both before and after the core handler.
+// Wrap and Use share the same chain; the first middleware added is the outermost.
+// Errors returned by the chain can be handled by Resolve, which always runs outside of the chain with the context
+// the chain was entered with.
+func (r *Route) Wrap(wrappers ...Wrapper) *Route {
+	r.middlewares = append(r.middlewares, wrappers...)
+	return r
+}
+
+// ParseWith sets the CmdParser used to tokenize message content, such as ShellParser.
+//
+// It takes priority over a Handler implementing CmdParser and the default parser of the Router.
+// Like the Prefixer, it is only used if the Route is added to the Router as a root-level route.
+func (r *Route) ParseWith(p CmdParser) *Route {
+	r.parser = p
+	return r
+}
+
+// Args declares the positional arguments of the route.
+//
+// If declared, arguments are converted by the Converter of their ArgType immediately before Handle,
+// and can be accessed through CmdContext. A missing, extra or unconvertible argument will skip Handle
+// and enter Resolve with an *ArgError.
+//
+// Args panics if a variadic argument is not the last argument, or if a required argument follows an optional argument,
+// including arguments declared by previous calls.
+func (r *Route) Args(args ...Arg) *Route {
+	r.args = append(r.args, args...)
+	if err := validateArgs(r.args); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Flags declares the flags of the route.
+//
+// If declared, flags are separated from positional arguments after middlewares run and immediately before Args
+// are converted, so Args only contains positional arguments and flags can be accessed with utils.GetFlags.
+// Middlewares receive the arguments before flags are separated.
+// An unknown or malformed flag will skip Handle and enter Resolve with a *FlagError.
+func (r *Route) Flags(flags ...Flag) *Route {
+	r.flags = append(r.flags, flags...)
+	return r
+}
+
+// Meta describes the route for the help command. See NewHelp.
+//
+// If Meta is called multiple times, the previous Meta call will be overwritten.
+func (r *Route) Meta(m Meta) *Route {
+	r.meta = m
+	return r
+}
+
+// Require declares the Discord permissions the author of the message and the bot need to invoke the route.
+//
+// Permissions are checked before any middleware runs. If a permission is missing, Handle is skipped and Resolve is
+// entered with a *PermissionError listing the missing permissions.
+//
+// Permissions are inherited by subroutes, which require the permissions of every route from the root route down to
+// the subroute. A route invoked in a direct message is denied unless all of those routes allow direct messages.
+//
+// If Require is called multiple times, the previous Require call will be overwritten.
+func (r *Route) Require(p Permissions) *Route {
+	r.perms = &p
+	return r
+}
+
+// Filter prevents messages meeting the criteria of f from invoking the route, such as filter.MsgFromBot.
+//
+// Filters are checked before anything else, including middlewares, permissions and the handling of parse errors.
+// policy determines whether a rejected message is silently dropped or enters Resolve with a *filter.Error.
+//
+// Filters are inherited by subroutes. If Filter is called multiple times, the filters are combined
+// and the last policy applies.
+func (r *Route) Filter(f filter.Filter, policy FilterPolicy) *Route {
+	r.filter = filter.New(r.filter, f)
+	r.filterPolicy = policy
+	return r
+}
+
+// Confirm asks the author of the message to confirm c before the route is handled, such as for destructive commands.
+// The prompt is sent after middlewares run and arguments are converted. If it is denied or not answered in time,
+// Handle is skipped and Resolve is entered with ErrDenied or ErrConfirmTimeout.
+//
+// Confirm only applies to the route itself, not its subroutes. If c is nil, the route is handled without confirmation.
+func (r *Route) Confirm(c *Confirm) *Route {
+	r.confirm = c
+	return r
+}
+
+// Isolate prevents the route and its subroutes from inheriting the filters, middlewares and permissions of parent routes,
+// such as a public help subcommand of a privileged route. Middlewares of the Router still apply.
+func (r *Route) Isolate() *Route {
+	r.isolated = true
 	return r
 }
 
@@ -216,7 +474,7 @@ func NewRoute(p Prefixer) *Route {
 		p:           p,
 		aliases:     []string{},
 		subroutes:   []*Route{},
-		middlewares: []Middlewarer{},
+		middlewares: []Wrapper{},
 	}
 }
 
@@ -225,7 +483,7 @@ func NewSubroute() *Route {
 	return &Route{
 		aliases:     []string{},
 		subroutes:   []*Route{},
-		middlewares: []Middlewarer{},
+		middlewares: []Wrapper{},
 	}
 }
 
@@ -247,47 +505,12 @@ func createHandlerFunc(route *Route) handlerFunc {
 		return nil
 	}
 
-	return func(ctx context.Context) {
-		var (
-			ok  bool
-			msg = utils.GetMsg(ctx)
-			cmd = msg.Content
-		)
-
-		prefix := route.getGuildPrefix(msg.GuildID)
-		ctx = utils.WithPrefix(ctx, prefix)
-		if cmd, ok = trimPrefix(cmd, prefix); !ok {
-			return
-		}
-
-		args, err := handleParse(route.h, cmd)
-		if err != nil {
-			ctx = utils.WithErr(ctx, err)
-			handleResolve(route.h)(ctx)
-			return
-		}
-
-		route, depth := findRouteRecursive(route, args, 1)
-		if route == nil {
-			return
-		}
+	idx := newRouteIndex([]*rootRoute{{route: route}}, nil)
 
-		ctx = utils.WithAlias(ctx, args[:depth])
-		ctx = utils.WithArgs(ctx, args[depth:])
-
-		if err = handleMiddlewares(ctx, route.middlewares); err != nil {
-			ctx = utils.WithErr(ctx, err)
-			handleResolve(route.h)(ctx)
-			return
-		}
-
-		err = route.h.Handle(ctx)
-		if err != nil {
-			ctx = utils.WithErr(ctx, err)
+	return func(ctx context.Context) {
+		for _, inv := range idx.invocations(ctx) {
+			inv.run()
 		}
-
-		handleResolve(route.h)(ctx)
-
 	}
 }
 
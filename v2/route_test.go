@@ -2,11 +2,17 @@ package v2
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/pixeltopic/sayori/v2/filter"
 	"github.com/pixeltopic/sayori/v2/utils"
 )
 
@@ -35,6 +41,29 @@ func makeMockSes() *discordgo.Session {
 	return session
 }
 
+// testServerTransport sends every request to a test server instead of Discord.
+type testServerTransport struct {
+	url *url.URL
+}
+
+func (tr testServerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = tr.url.Scheme, tr.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// makeMockRESTSes returns a mock session whose REST requests are handled by h.
+// The returned server must be closed by the caller.
+func makeMockRESTSes(h http.HandlerFunc) (*discordgo.Session, *httptest.Server) {
+	srv := httptest.NewServer(h)
+	u, _ := url.Parse(srv.URL)
+
+	session := makeMockSes()
+	session.Client = &http.Client{Transport: testServerTransport{url: u}}
+	session.Ratelimiter = discordgo.NewRatelimiter()
+
+	return session, srv
+}
+
 func TestRoute_copyRoute(t *testing.T) {
 
 	// initialize route and subroutes
@@ -459,3 +488,138 @@ func TestRoute_createHandlerFunc(t *testing.T) {
 	}
 
 }
+
+func TestRoute_Filter(t *testing.T) {
+	var (
+		ses      = makeMockSes()
+		router   = New(ses)
+		trace    []string
+		resolved error
+	)
+
+	cmd := func(name string) *testCmd {
+		return &testCmd{
+			HandleCallback: func(_ context.Context) error {
+				trace = append(trace, name)
+				return nil
+			},
+			ResolveCallback: func(ctx context.Context) {
+				resolved = utils.GetErr(ctx)
+			},
+		}
+	}
+
+	router.Has(NewRoute(&testPref{}).On("echo").Do(cmd("echo")).ParseWith(ShellParser{}).
+		Use(&testMiddleware{name: "middleware", trace: &trace}).
+		Filter(filter.MsgFromBot, FilterResolve))
+	router.Has(NewRoute(&testPref{}).On("quiet").Do(cmd("quiet")).
+		Filter(filter.New(filter.MsgFromBot, filter.MsgFromWebhook), FilterDrop).
+		Has(NewSubroute().On("public").Do(cmd("public")).Isolate()))
+
+	testCases := []struct {
+		content  string
+		bot      bool
+		expected []string
+		failed   []filter.Filter // expected flags of the resolved *filter.Error, nil if not resolved
+	}{
+		{content: "echo hi", expected: []string{"middleware", "echo"}},
+		{content: "echo hi", bot: true, failed: []filter.Filter{filter.MsgFromBot}},
+		{content: `echo "hi`, bot: true, failed: []filter.Filter{filter.MsgFromBot}},
+		{content: "quiet", expected: []string{"quiet"}},
+		{content: "quiet", bot: true},
+		{content: "quiet public", bot: true, expected: []string{"public"}},
+	}
+
+	for _, c := range testCases {
+		trace, resolved = nil, nil
+
+		m := makeMockMsg(testDefaultPrefix + c.content)
+		m.Author.Bot = c.bot
+		router.onMessageCreate(ses, m)
+
+		if !strSliceEqual(c.expected, trace, false) {
+			t.Errorf("%q: expected trace %v, got %v", c.content, c.expected, trace)
+		}
+
+		var filterErr *filter.Error
+		switch {
+		case c.failed == nil && resolved != nil:
+			t.Errorf("%q: expected no error, got %v", c.content, resolved)
+		case c.failed != nil && !errors.As(resolved, &filterErr):
+			t.Errorf("%q: expected a filter Error, got %v", c.content, resolved)
+		case c.failed != nil && !reflect.DeepEqual(c.failed, filterErr.Failed()):
+			t.Errorf("%q: expected failed filters %v, got %v", c.content, c.failed, filterErr.Failed())
+		}
+	}
+}
+
+// testMultiPref accepts "!", "?" and "bot" case-insensitively with optional whitespace.
+type testMultiPref struct{}
+
+func (p *testMultiPref) Load(_ string) (string, bool) { return p.Default(), true }
+
+func (*testMultiPref) Default() string { return "!" }
+
+func (*testMultiPref) LoadAll(_ string) ([]string, bool) { return []string{"!", "?", "bot", "b"}, true }
+
+func (*testMultiPref) PrefixOptions() PrefixOptions {
+	return PrefixOptions{CaseInsensitive: true, TrimSpace: true}
+}
+
+func TestPrefixSet_trim(t *testing.T) {
+	testCases := []struct {
+		ps          prefixSet
+		command     string
+		prefix, cmd string
+		expectedOk  bool
+	}{
+		{ps: prefixSet{prefixes: []string{"t!"}}, command: "t!echo", prefix: "t!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"t!"}}, command: "T!echo"},
+		{ps: prefixSet{prefixes: []string{"t!"}}, command: "t!"},
+		{ps: prefixSet{prefixes: []string{""}}, command: "echo", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{""}}, command: ""},
+		{ps: prefixSet{prefixes: []string{"!", "!!"}}, command: "!!echo", prefix: "!!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"!!", "!"}}, command: "!!"},
+		{ps: loadPrefixes(&testMultiPref{}, ""), command: "Bot  help", prefix: "Bot", cmd: "help", expectedOk: true},
+		{ps: loadPrefixes(&testMultiPref{}, ""), command: "bhelp", prefix: "b", cmd: "help", expectedOk: true},
+		{ps: loadPrefixes(&testMultiPref{}, ""), command: "bot "},
+		{ps: loadPrefixes(&testPref{}, ""), command: testDefaultPrefix + "help", prefix: testDefaultPrefix, cmd: "help", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"é!"}}, command: "é!echo", prefix: "é!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"é!"}}, command: "É!echo"},
+		{ps: prefixSet{prefixes: []string{"é!"}, opts: PrefixOptions{CaseInsensitive: true}}, command: "É!echo", prefix: "É!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"k!"}, opts: PrefixOptions{CaseInsensitive: true}}, command: "\u212a!echo", prefix: "\u212a!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"\u212a!"}, opts: PrefixOptions{CaseInsensitive: true}}, command: "K!echo", prefix: "K!", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"ß"}, opts: PrefixOptions{CaseInsensitive: true}}, command: "\u1e9eecho", prefix: "\u1e9e", cmd: "echo", expectedOk: true},
+		{ps: prefixSet{prefixes: []string{"ä"}, opts: PrefixOptions{CaseInsensitive: true}}, command: "a\u0308echo"},
+	}
+
+	for _, c := range testCases {
+		prefix, cmd, ok := c.ps.trim(c.command)
+		if prefix != c.prefix || cmd != c.cmd || ok != c.expectedOk {
+			t.Errorf("%q with %v: expected (%q, %q, %v), got (%q, %q, %v)",
+				c.command, c.ps.prefixes, c.prefix, c.cmd, c.expectedOk, prefix, cmd, ok)
+		}
+	}
+}
+
+func TestRouter_MultiPrefixer(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		fired  []string
+	)
+
+	router.Has(NewRoute(&testMultiPref{}).On("help").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		fired = append(fired, utils.GetPrefix(ctx)+":"+strings.Join(utils.GetArgs(ctx), " "))
+		return nil
+	}}))
+
+	for _, content := range []string{"!help", "? help me", "BOT help", "bothelp", "help", "!!help"} {
+		router.onMessageCreate(ses, makeMockMsg(content))
+	}
+
+	expected := []string{"!:", "?:me", "BOT:", "bot:"}
+	if !strSliceEqual(expected, fired, false) {
+		t.Errorf("expected handlers %v to fire, got %v", expected, fired)
+	}
+}
@@ -2,6 +2,8 @@ package v2
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/pixeltopic/sayori/v2/utils"
 
@@ -9,14 +11,48 @@ import (
 )
 
 // Router maps commands to handlers.
+//
+// All routes bound with Has or HasOnce share a single DiscordGo MessageCreate handler,
+// which looks up matching routes with one tokenization and one walk of an alias trie per message.
+// Root routes whose Handler implements CmdParser are tokenized separately.
 type Router struct {
 	S *discordgo.Session
+
+	mu               sync.RWMutex
+	seq              uint64
+	roots            []*rootRoute
+	idx              *routeIndex // nil if roots changed since the index was last built
+	registered       bool
+	exclusive        bool
+	fallback         *rootRoute // only used in exclusive mode
+	parser           CmdParser
+	suggest          *SuggestOptions
+	wrappers         []Wrapper
+	mention          bool
+	edits            *time.Duration // nil unless edited messages are handled by every route
+	reactions        []*reactionRoot
+	reactionWrappers []Wrapper
+	pool             *workerPool    // nil unless handlers run on a worker pool, or if the Router is closed
+	poolOpts         *WorkerOptions // options of the worker pool, to restart it when the Router is reopened
+
+	base     context.Context // parent of every invocation context, cancelled on Close
+	cancel   context.CancelFunc
+	closing  bool            // if true, new events are not handled
+	closed   bool            // if true, events can no longer be awaited; set by Close until the Router is reopened
+	inflight *sync.WaitGroup // events being handled since the Router was last opened; only incremented while not closing
+
+	waitMu  sync.Mutex
+	waiters map[*waiter]struct{} // pending calls of Await
 }
 
 // New returns a new Router.
 func New(s *discordgo.Session) *Router {
+	base, cancel := context.WithCancel(context.Background())
 	return &Router{
-		S: s,
+		S:        s,
+		base:     base,
+		cancel:   cancel,
+		inflight: &sync.WaitGroup{},
 	}
 }
 
@@ -37,29 +73,278 @@ func (r *Router) HasOnceDefault(h interface{}) func() {
 	return r.addHandlerOnce(h)
 }
 
-func makeHandlerForDgo(route Route) func(*discordgo.Session, *discordgo.MessageCreate) {
-	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
-		ctx := context.Background()
+// onMessageCreate is the DiscordGo MessageCreate handler shared by all routes bound to the Router.
+func (r *Router) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	ctx := withRouter(utils.WithSes(utils.WithMsg(r.baseContext(), m.Message), s), r)
 
-		// finds deepest subroute and executes its handler with an accumulated context
-		createHandlerFunc(&route)(utils.WithSes(utils.WithMsg(ctx, m.Message), s))
+	r.notifyWaiters(ctx)
+	r.dispatch(ctx)
+}
+
+// dispatch runs the routes that should handle the message in ctx.
+func (r *Router) dispatch(ctx context.Context) {
+	// finds deepest subroute of every matching root route and executes its handler with an accumulated context
+	done, ok := r.begin()
+	if !ok {
+		return
+	}
+	idx := r.index()
+	if idx.blocksEvents(utils.GetSes(ctx)) {
+		ctx = withBlocking(ctx)
 	}
+	invs := idx.selectInvocations(ctx)
+	if len(invs) == 0 {
+		done()
+		return
+	}
+
+	concurrent := len(invs) > 1 && idx.concurrent(utils.GetSes(ctx))
+	idx.execute(ctx, done, func() {
+		var wg sync.WaitGroup
+		for _, inv := range invs {
+			if !inv.claim() {
+				continue
+			}
+			if inv.root != nil && inv.root.once {
+				r.remove(inv.root)
+			}
+			if !concurrent {
+				inv.run()
+				continue
+			}
+			// a route blocking in Await or a Paginator does not delay other routes handling the message
+			wg.Add(1)
+			go func(inv *invocation) {
+				defer wg.Done()
+				inv.run()
+			}(inv)
+		}
+		wg.Wait()
+	})
 }
 
-// Has binds a Route to the Router.
-func (r *Router) Has(route *Route) func() {
+// selectInvocations returns the invocations that should run for the message in ctx.
+// If suggestions are enabled and the message contains an unknown root command, the invocation responding to it runs first.
+//
+// In exclusive mode, at most one invocation is returned.
+// If the message was edited, only routes handling edits are selected, and unknown commands are only responded to
+// if every route handles edits.
+func (idx *routeIndex) selectInvocations(ctx context.Context) []*invocation {
+	var (
+		edit = utils.IsEdit(ctx)
+		invs = idx.invocations(ctx)
+	)
+	if edit {
+		invs = idx.editInvocations(ctx, invs)
+	}
+
+	if idx.suggest != nil && idx.suggest.Respond != nil && (!edit || idx.edits != nil) && !matchedAlias(invs) {
+		if unknown := idx.unknownCommand(ctx); unknown != nil {
+			if idx.exclusive {
+				return []*invocation{unknown}
+			}
+			invs = append([]*invocation{unknown}, invs...)
+		}
+	}
+
+	if !idx.exclusive {
+		return invs
+	}
+	if inv := best(invs, false); inv != nil && inv.depth > 0 {
+		return []*invocation{inv}
+	}
+	if inv := best(invs, true); inv != nil && inv.depth > 0 {
+		return []*invocation{inv}
+	}
+	if idx.fallback != nil {
+		fallback := idx.fallback.invocations(ctx)
+		if edit {
+			fallback = idx.fallback.editInvocations(ctx, fallback)
+		}
+		if len(fallback) != 0 {
+			return fallback
+		}
+	}
+	if inv := best(invs, false); inv != nil {
+		return []*invocation{inv}
+	}
+	return nil
+}
+
+// Exclusive enables exclusive dispatch, where at most one route handles a message.
+//
+// The deepest matching route across all root routes is selected; if multiple routes match at the same depth,
+// the most recently added root route wins. If the message fails to parse, the parse error is resolved by the root route
+// whose aliases match the message split on whitespace, following the same rules.
+//
+// fallback is run only when no aliased route matches, and may be nil. Like any root route, its Prefixer must match
+// for it to run. Root routes with no aliases match any message, so they are only selected when neither an aliased route
+// nor the fallback route matches, such as when the Prefixer of the fallback route does not match.
+func (r *Router) Exclusive(fallback *Route) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exclusive = true
+	r.fallback = nil
+	if fallback != nil {
+		copied := copyRoute(*fallback)
+		r.fallback = &rootRoute{route: &copied}
+	}
+	r.idx = nil
+
+	r.ensureRegistered()
+	return r
+}
+
+// ParseWith sets the default CmdParser of the Router, such as ShellParser.
+//
+// It is used by root routes that neither call Route.ParseWith nor have a Handler implementing CmdParser.
+// If p is nil, messages are split on whitespace.
+//
+// If a message fails to parse, the error is resolved by the root routes whose aliases match the message split on
+// whitespace. Root routes with no aliases ignore messages that fail to parse.
+func (r *Router) ParseWith(p CmdParser) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.parser = p
+	r.idx = nil
+	return r
+}
+
+// Use adds middlewares that run for every route bound to the Router, before the middlewares of the route.
+//
+// Like Route.Use, middlewares are executed in order of which they were added, and errors can be handled by Resolve.
+// They do not run for reaction routes, whose Context has no message; see UseReaction.
+func (r *Router) Use(middlewares ...Middlewarer) *Router {
+	wrappers := make([]Wrapper, 0, len(middlewares))
+	for _, m := range middlewares {
+		wrappers = append(wrappers, Adapt(m))
+	}
+	return r.Wrap(wrappers...)
+}
+
+// Wrap adds wrapping middlewares that run for every route bound to the Router, before the middlewares of the route.
+// Wrap and Use share the same chain; the first middleware added is the outermost.
+func (r *Router) Wrap(wrappers ...Wrapper) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.wrappers = append(r.wrappers[:len(r.wrappers):len(r.wrappers)], wrappers...)
+	r.idx = nil
+	return r
+}
+
+// MentionPrefix lets users invoke routes by mentioning the bot, such as "@Bot help", in addition to their prefix.
+//
+// A mention of the bot user in Session.State (<@id> or <@!id>) followed by optional whitespace is accepted as the prefix
+// of every root route with a Prefixer. It takes priority over the prefix loaded by the Prefixer, and the mention
+// as written is recorded as the prefix of the invocation. Routes without a Prefixer are unaffected.
+func (r *Router) MentionPrefix() *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mention = true
+	r.idx = nil
+	return r
+}
+
+// index returns the route index, rebuilding it if routes or options of the Router changed.
+func (r *Router) index() *routeIndex {
+	r.mu.RLock()
+	idx := r.idx
+	r.mu.RUnlock()
+	if idx != nil {
+		return idx
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.idx == nil {
+		r.idx = newRouteIndex(r.roots, r.parser)
+		r.idx.exclusive = r.exclusive
+		r.idx.suggest = r.suggest
+		r.idx.wrappers = r.wrappers
+		r.idx.mention = r.mention
+		r.idx.edits = r.edits
+		r.idx.reactions = r.reactions
+		r.idx.reactionWrappers = r.reactionWrappers
+		r.idx.pool = r.pool
+		r.idx.editable = r.idx.editable || r.edits != nil
+		if r.fallback != nil {
+			r.idx.fallback = newRouteIndex([]*rootRoute{r.fallback}, r.parser)
+			r.idx.fallback.wrappers = r.wrappers
+			r.idx.fallback.mention = r.mention
+			r.idx.fallback.edits = r.edits
+			r.idx.editable = r.idx.editable || r.idx.fallback.editable
+		}
+	}
+	return r.idx
+}
+
+// add registers a copy of route as a root route and returns a function that will remove it.
+func (r *Router) add(route *Route, once bool) func() {
 	if route == nil {
 		return nil
 	}
-	return r.addHandler(makeHandlerForDgo(copyRoute(*route)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := copyRoute(*route)
+	r.seq++
+	root := &rootRoute{route: &copied, seq: r.seq, once: once}
+	r.roots = append(r.roots, root)
+	r.idx = nil
+
+	r.ensureRegistered()
+
+	return func() { r.remove(root) }
+}
+
+// ensureRegistered adds the message and reaction handlers of the Router to the session if they were not already added.
+//
+// r.mu must be held.
+func (r *Router) ensureRegistered() {
+	if !r.registered && r.S != nil {
+		r.S.AddHandler(r.onMessageCreate)
+		r.S.AddHandler(r.onMessageUpdate)
+		r.S.AddHandler(r.onMessageReactionAdd)
+		r.S.AddHandler(r.onMessageReactionRemove)
+		r.registered = true
+	}
+}
+
+// remove unbinds a root route from the Router. No-ops if it was already removed.
+func (r *Router) remove(root *rootRoute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, candidate := range r.roots {
+		if candidate == root {
+			r.roots = append(r.roots[:i:i], r.roots[i+1:]...)
+			r.idx = nil
+			return
+		}
+	}
+}
+
+// Has binds a Route to the Router.
+//
+// Root routes handling the same message run on their own goroutines, as separate DiscordGo handlers would.
+// If Session.SyncEvents is set or handlers run on a worker pool, they run one after another instead.
+//
+// It returns a function that will remove the route when executed.
+func (r *Router) Has(route *Route) func() {
+	return r.add(route, false)
 }
 
 // HasOnce binds binds a Route to the Router, but the route will only fire at most once.
+// The route is removed after it first handles a message.
+//
+// It returns a function that will remove the route when executed.
 func (r *Router) HasOnce(route *Route) func() {
-	if route == nil {
-		return nil
-	}
-	return r.addHandlerOnce(makeHandlerForDgo(copyRoute(*route)))
+	return r.add(route, true)
 }
 
 func (r *Router) addHandler(h interface{}) func() {
@@ -76,13 +361,110 @@ func (r *Router) addHandlerOnce(h interface{}) func() {
 	return nil
 }
 
+// baseContext returns the Context every invocation context of the Router is derived from.
+func (r *Router) baseContext() context.Context {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.base == nil {
+		return context.Background()
+	}
+	return r.base
+}
+
+// begin reports if the Router accepts a new event, tracking it as in flight until the returned func is called.
+func (r *Router) begin() (func(), bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closing {
+		return nil, false
+	}
+	if r.inflight == nil {
+		return func() {}, true // not created with New
+	}
+	inflight := r.inflight
+	inflight.Add(1)
+	return inflight.Done, true
+}
+
 // Open creates a websocket connection to Discord.
 // See: https://discordapp.com/developers/docs/topics/gateway#connecting
+//
+// A Router that was closed handles events again once it is reopened.
 func (r *Router) Open() error {
+	r.reopen()
 	return r.S.Open()
 }
 
+// reopen resets a closed Router so it handles events again.
+func (r *Router) reopen() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closing {
+		r.closing, r.closed = false, false
+		r.base, r.cancel = context.WithCancel(context.Background())
+		r.inflight = &sync.WaitGroup{} // a Shutdown that timed out may still be waiting on the previous one
+		if r.poolOpts != nil {
+			r.pool = newWorkerPool(*r.poolOpts)
+			r.idx = nil
+		}
+	}
+}
+
 // Close closes a websocket and stops all listening/heartbeat goroutines.
+//
+// The Router stops handling events, and the Context of every running handler is cancelled. Pending calls of Await
+// return ErrRouterClosed, and the worker pool is stopped after its queued messages are handled.
+// Close does not wait for running handlers; use Shutdown to wait for them.
 func (r *Router) Close() error {
+	r.mu.Lock()
+	r.closing, r.closed = true, true
+	if r.cancel != nil {
+		r.cancel()
+	}
+	pool := r.pool
+	r.pool = nil
+	r.idx = nil
+	r.mu.Unlock()
+
+	// stopped without holding r.mu, as queued handlers may need it
+	if pool != nil {
+		pool.stop()
+	}
+
+	r.closeWaiters()
 	return r.S.Close()
 }
+
+// Shutdown gracefully closes the Router. New events are no longer handled, then Shutdown waits for running handlers,
+// including their Resolve and messages queued on the worker pool, to finish before calling Close.
+// Handlers waiting in Await still receive messages until they finish.
+//
+// If ctx is done before handlers finish, the Router is closed anyway, cancelling the Context of running handlers,
+// and Shutdown returns ctx.Err().
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closing = true
+	inflight := r.inflight
+	r.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		if inflight != nil {
+			inflight.Wait()
+		}
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
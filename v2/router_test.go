@@ -0,0 +1,522 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// testHandler is a Handler that does not implement CmdParser, so routes using it share the default parser.
+type testHandler struct{}
+
+func (testHandler) Handle(_ context.Context) error { return nil }
+
+// testBuildRoutes returns n root routes, each with aliases "cmd<i>" and "c<i>", and a shared subroute tree.
+func testBuildRoutes(n int, p Prefixer) []*Route {
+	routes := make([]*Route, 0, n)
+	for i := 0; i < n; i++ {
+		routes = append(routes, NewRoute(p).On(fmt.Sprintf("cmd%d", i), fmt.Sprintf("c%d", i)).Do(testHandler{}).Has(
+			NewSubroute().On("add", "a").Do(testHandler{}).Has(
+				NewSubroute().On("user").Do(testHandler{}),
+			),
+			NewSubroute().On("remove", "rm").Do(testHandler{}),
+			NewSubroute().On("add").Do(testHandler{}),
+			NewSubroute().On("list", "ls").Do(testHandler{}),
+		))
+	}
+	return routes
+}
+
+func TestRouteIndex_find(t *testing.T) {
+	root := NewRoute(nil).On("root", "r").Do(&testCmd{}).Has(
+		NewSubroute().On("sub1").Do(&testCmd{}).Has(
+			NewSubroute().On("subsub1").Do(&testCmd{}).Has(
+				NewSubroute().On("sub1").Do(&testCmd{}),
+			),
+		),
+		NewSubroute().On("SUB1", "s").Do(&testCmd{}).Has(
+			NewSubroute().On("subsub1").Do(&testCmd{}),
+			NewSubroute().Do(&testCmd{}),
+		),
+		NewSubroute().On("sub2").Do(&testCmd{}),
+	)
+
+	idx := newRouteIndex([]*rootRoute{{route: root}}, nil)
+
+	for _, content := range []string{
+		"root",
+		"R sub1",
+		"root sub1 subsub1",
+		"root sub1 subsub1 sub1 arg",
+		"root s subsub1 arg",
+		"root s arg subsub1",
+		"root sub2 sub1",
+		"root arg",
+		"rooot sub1",
+		"sub1",
+	} {
+		args := cmdParserDefault(content)
+		want, wantDepth := findRouteRecursive(root, args, 1)
+
+		matches := idx.groups[0].find(args)
+		if want == nil {
+			if len(matches) != 0 {
+				t.Errorf("%q: expected no match, got %d", content, len(matches))
+			}
+			continue
+		}
+		if len(matches) != 1 {
+			t.Errorf("%q: expected 1 match, got %d", content, len(matches))
+			continue
+		}
+		if matches[0].route != want || matches[0].depth != wantDepth {
+			t.Errorf("%q: trie match differs from findRouteRecursive; got depth %d, want depth %d",
+				content, matches[0].depth, wantDepth)
+		}
+	}
+}
+
+func TestRouter_Has(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		mu     sync.Mutex // routes handling the same message run concurrently
+		fired  []string
+	)
+
+	record := func(name string) *testCmd {
+		return &testCmd{HandleCallback: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			fired = append(fired, name+":"+strings.Join(utils.GetArgs(ctx), " "))
+			return nil
+		}}
+	}
+
+	router.Has(NewRoute(&testPref{}).On("echo").Do(record("echo")).Has(
+		NewSubroute().On("fmt").Do(record("fmt")),
+	))
+	router.Has(NewRoute(nil).Do(record("default")))
+	removeEcho2 := router.Has(NewRoute(&testPref{}).On("ECHO").Do(record("echo2")))
+	router.HasOnce(NewRoute(&testPref{}).On("once").Do(record("once")))
+
+	testCases := []struct {
+		content  string
+		expected []string
+	}{
+		{content: testDefaultPrefix + "echo fmt hi", expected: []string{"fmt:hi", "echo2:fmt hi", "default:" + testDefaultPrefix + "echo fmt hi"}},
+		{content: "echo fmt hi", expected: []string{"default:echo fmt hi"}},
+		{content: testDefaultPrefix + "once a", expected: []string{"once:a", "default:" + testDefaultPrefix + "once a"}},
+		{content: testDefaultPrefix + "once a", expected: []string{"default:" + testDefaultPrefix + "once a"}},
+	}
+
+	for _, c := range testCases {
+		fired = nil
+		router.onMessageCreate(ses, makeMockMsg(c.content))
+		if !strSliceEqual(c.expected, fired, true) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", c.content, c.expected, fired)
+		}
+	}
+
+	removeEcho2()
+	fired = nil
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"echo"))
+	if expected := []string{"echo:", "default:" + testDefaultPrefix + "echo"}; !strSliceEqual(expected, fired, true) {
+		t.Errorf("expected handlers %v to fire after removal, got %v", expected, fired)
+	}
+}
+
+func TestRouter_Has_concurrent(t *testing.T) {
+	var (
+		ses     = makeMockSes()
+		router  = New(ses)
+		handled = make(chan struct{})
+	)
+
+	// the blocking route only returns once the route added after it handled the same message
+	router.Has(NewRoute(&testPref{}).On("wait").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Error("expected routes handling the same message to run concurrently")
+		}
+		return nil
+	}}))
+	router.Has(NewRoute(nil).Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		close(handled)
+		return nil
+	}}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"wait"))
+}
+
+func TestRouter_MentionPrefix(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		fired  []string
+	)
+
+	record := func(name string) *testCmd {
+		return &testCmd{HandleCallback: func(ctx context.Context) error {
+			fired = append(fired, name+":"+utils.GetPrefix(ctx)+":"+strings.Join(utils.GetArgs(ctx), " "))
+			return nil
+		}}
+	}
+
+	router.Has(NewRoute(&testPref{}).On("help").Do(record("help")))
+	router.Has(NewRoute(nil).On("echo").Do(record("echo")))
+
+	router.onMessageCreate(ses, makeMockMsg("<@self_id_1> help"))
+	if len(fired) != 0 {
+		t.Errorf("expected mentions to be ignored before MentionPrefix, got %v", fired)
+	}
+
+	router.MentionPrefix()
+
+	testCases := []struct {
+		content  string
+		expected []string
+	}{
+		{content: "<@self_id_1> help me", expected: []string{"help:<@self_id_1>:me"}},
+		{content: "<@!self_id_1>help", expected: []string{"help:<@!self_id_1>:"}},
+		{content: testDefaultPrefix + "help", expected: []string{"help:" + testDefaultPrefix + ":"}},
+		{content: "<@self_id_1>"},
+		{content: "<@other_id_1> help"},
+		{content: "<@self_id_1> echo"},
+		{content: "echo", expected: []string{"echo::"}},
+	}
+
+	for _, c := range testCases {
+		fired = nil
+		router.onMessageCreate(ses, makeMockMsg(c.content))
+		if !strSliceEqual(c.expected, fired, true) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", c.content, c.expected, fired)
+		}
+	}
+}
+
+func TestRouter_HandleEdits(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		fired  []string
+	)
+
+	record := func(name string) *testCmd {
+		return &testCmd{ParseCallback: ShellParser{}.Parse, HandleCallback: func(ctx context.Context) error {
+			before := "?"
+			if msg := utils.GetBeforeEdit(ctx); msg != nil {
+				before = msg.Content
+			}
+			fired = append(fired, fmt.Sprintf("%s:%v:%s", name, utils.IsEdit(ctx), before))
+			return nil
+		}}
+	}
+
+	edit := func(content, before string, age time.Duration) *discordgo.MessageUpdate {
+		msg := makeMockMsg(content).Message
+		msg.Timestamp = discordgo.Timestamp(time.Now().Add(-age).Format(time.RFC3339))
+		msg.EditedTimestamp = discordgo.Timestamp(time.Now().Format(time.RFC3339))
+
+		m := &discordgo.MessageUpdate{Message: msg}
+		if before != "" {
+			m.BeforeUpdate = makeMockMsg(before).Message
+		}
+		return m
+	}
+
+	router.Has(NewRoute(&testPref{}).On("ban").Do(record("ban")).HandleEdits(time.Minute))
+	router.Has(NewRoute(&testPref{}).On("kick").Do(record("kick")).Has(
+		NewSubroute().On("all").Do(record("kick all")).HandleEdits(0),
+	))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"ban"))
+	if expected := []string{"ban:false:?"}; !strSliceEqual(expected, fired, false) {
+		t.Errorf("expected new messages to be handled as before, got %v", fired)
+	}
+
+	testCases := []struct {
+		m        *discordgo.MessageUpdate
+		expected []string
+	}{
+		{m: edit(testDefaultPrefix+"ban", testDefaultPrefix+"bna", time.Second), expected: []string{"ban:true:" + testDefaultPrefix + "bna"}},
+		{m: edit(testDefaultPrefix+"ban", "hello", time.Second), expected: []string{"ban:true:hello"}},
+		{m: edit(testDefaultPrefix+"ban", "", time.Second), expected: []string{"ban:true:?"}},
+		{m: edit(testDefaultPrefix+"ban", "", time.Hour)},
+		{m: edit(testDefaultPrefix+"ban", "hello", time.Hour)},
+		{m: edit(testDefaultPrefix+"ban", testDefaultPrefix+"ban", time.Second)},
+		{m: edit(testDefaultPrefix+"ban user_2", testDefaultPrefix+"ban user_1", time.Second)},
+		{m: edit(testDefaultPrefix+"ban", testDefaultPrefix+"ban \"unterminated", time.Second), expected: []string{"ban:true:" + testDefaultPrefix + "ban \"unterminated"}},
+		{m: edit(testDefaultPrefix+"kick", "hello", time.Second)},
+		{m: edit(testDefaultPrefix+"kick all", testDefaultPrefix+"kick", 24*time.Hour), expected: []string{"kick all:true:" + testDefaultPrefix + "kick"}},
+		{m: edit(testDefaultPrefix+"kick all", testDefaultPrefix+"kick all", 24*time.Hour)},
+		{m: edit(testDefaultPrefix+"kick all", "", 24*time.Hour), expected: []string{"kick all:true:?"}},
+		{m: edit(testDefaultPrefix+"kick", "", time.Second)},
+		{m: &discordgo.MessageUpdate{Message: makeMockMsg(testDefaultPrefix + "ban").Message}},
+	}
+
+	for _, c := range testCases {
+		fired = nil
+		router.onMessageUpdate(ses, c.m)
+		if !strSliceEqual(c.expected, fired, false) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", c.m.Content, c.expected, fired)
+		}
+	}
+
+	router.HandleEdits(0)
+	fired = nil
+	router.onMessageUpdate(ses, edit(testDefaultPrefix+"kick", "hello", time.Hour))
+	if expected := []string{"kick:true:hello"}; !strSliceEqual(expected, fired, false) {
+		t.Errorf("expected Router.HandleEdits to apply to every route, got %v", fired)
+	}
+}
+
+func TestRouter_Exclusive(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses)
+		fired  []string
+	)
+
+	record := func(name string) *testCmd {
+		return &testCmd{HandleCallback: func(ctx context.Context) error {
+			fired = append(fired, name)
+			return nil
+		}}
+	}
+
+	router.Exclusive(NewRoute(&testPref{}).Do(record("unknown")))
+	router.Has(NewRoute(nil).Do(record("default")))
+	router.Has(NewRoute(&testPref{}).On("tag").Do(record("tag")).Has(
+		NewSubroute().On("create").Do(record("tag create")),
+	))
+	router.Has(NewRoute(&testPref{}).On("tag").Do(record("tag2")))
+	router.Has(NewRoute(&testPref{}).On("quote").Do(&testCmd{
+		ParseCallback: ShellParser{}.Parse,
+		HandleCallback: func(ctx context.Context) error {
+			fired = append(fired, "quote")
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			if _, ok := utils.GetErr(ctx).(*ParseError); ok {
+				fired = append(fired, "quote parse error")
+			}
+		},
+	}))
+
+	testCases := []struct {
+		content  string
+		expected []string
+	}{
+		{content: testDefaultPrefix + "tag create hello", expected: []string{"tag create"}},
+		{content: testDefaultPrefix + "tag hello", expected: []string{"tag2"}},
+		{content: testDefaultPrefix + "nope", expected: []string{"unknown"}},
+		{content: "nope", expected: []string{"default"}},
+		{content: testDefaultPrefix + `quote "hello"`, expected: []string{"quote"}},
+		{content: testDefaultPrefix + `quote "hello`, expected: []string{"quote parse error"}},
+		{content: testDefaultPrefix + `nope "hello`, expected: []string{"unknown"}},
+	}
+
+	for _, c := range testCases {
+		fired = nil
+		router.onMessageCreate(ses, makeMockMsg(c.content))
+		if !strSliceEqual(c.expected, fired, false) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", c.content, c.expected, fired)
+		}
+	}
+
+	router = New(ses).Exclusive(NewRoute(&testPref{}).Do(record("unknown")))
+	router.Has(NewRoute(&testPref{}).On("tag").Do(record("tag")))
+
+	for content, expected := range map[string][]string{
+		testDefaultPrefix + "nope": {"unknown"},
+		"nope":                     nil,
+	} {
+		fired = nil
+		router.onMessageCreate(ses, makeMockMsg(content))
+		if !strSliceEqual(expected, fired, false) {
+			t.Errorf("%q: expected handlers %v to fire, got %v", content, expected, fired)
+		}
+	}
+}
+
+func TestRouter_Shutdown(t *testing.T) {
+	var (
+		ses     = makeMockSes()
+		router  = New(ses)
+		started = make(chan context.Context, 1)
+		release = make(chan struct{})
+		fired   = make(chan string, 2)
+	)
+
+	router.Has(NewRoute(&testPref{}).On("slow").Do(&testCmd{
+		HandleCallback: func(ctx context.Context) error {
+			started <- ctx
+			<-release
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			fired <- "slow"
+		},
+	}))
+	router.Has(NewRoute(&testPref{}).On("fast").Do(testRecordCmd(fired, "fast")))
+
+	go router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"slow"))
+	hctx := <-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- router.Shutdown(context.Background()) }()
+
+	for !router.isClosing() {
+		time.Sleep(time.Millisecond)
+	}
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"fast"))
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected Shutdown to wait for running handlers, returned %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if hctx.Err() != nil {
+		t.Error("expected handler context to stay active while draining")
+	}
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if got := <-fired; got != "slow" {
+		t.Errorf("expected only the running handler to resolve, got %q", got)
+	}
+	if len(fired) != 0 {
+		t.Errorf("expected messages to be ignored after Shutdown, got %q", <-fired)
+	}
+	if hctx.Err() == nil {
+		t.Error("expected handler context to be cancelled once closed")
+	}
+}
+
+func TestRouter_Shutdown_deadline(t *testing.T) {
+	var (
+		ses     = makeMockSes()
+		router  = New(ses)
+		started = make(chan context.Context, 1)
+	)
+
+	router.Has(NewRoute(&testPref{}).On("stuck").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		started <- ctx
+		<-ctx.Done()
+		return ctx.Err()
+	}}))
+
+	go router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"stuck"))
+	hctx := <-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := router.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline to be exceeded, got %v", err)
+	}
+	<-hctx.Done()
+}
+
+func TestRouter_Shutdown_reopen(t *testing.T) {
+	var (
+		ses     = makeMockSes()
+		router  = New(ses)
+		started = make(chan struct{}, 2)
+		release = make(chan struct{})
+		handled = make(chan struct{}, 2)
+	)
+
+	router.Has(NewRoute(&testPref{}).On("slow").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		handled <- struct{}{}
+		return nil
+	}}))
+
+	go router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"slow"))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := router.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline to be exceeded, got %v", err)
+	}
+
+	// the handler of the previous generation is still running while the Router handles events again
+	router.reopen()
+	go router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"slow"))
+	<-started
+	close(release)
+	<-handled
+	<-handled
+
+	if err := router.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected Shutdown of the reopened Router to drain, got %v", err)
+	}
+}
+
+// testRecordCmd returns a testCmd sending name to fired when handled.
+func testRecordCmd(fired chan<- string, name string) *testCmd {
+	return &testCmd{HandleCallback: func(ctx context.Context) error {
+		fired <- name
+		return nil
+	}}
+}
+
+// isClosing reports if the Router stopped handling new events.
+func (r *Router) isClosing() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.closing
+}
+
+func BenchmarkRouter_invocations(b *testing.B) {
+	router := New(nil)
+	for _, route := range testBuildRoutes(150, &testPref{}) {
+		router.Has(route)
+	}
+	ctx := utils.WithSes(utils.WithMsg(context.Background(),
+		makeMockMsg(testDefaultPrefix+"cmd149 add user arg1 arg2").Message), makeMockSes())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(router.index().invocations(ctx)) != 1 {
+			b.Fatal("expected exactly 1 invocation")
+		}
+	}
+}
+
+// BenchmarkFindRouteRecursive_perRoute measures a prefix trim, parse and findRouteRecursive per root route,
+// which is the cost of binding each root route as its own DiscordGo handler.
+func BenchmarkFindRouteRecursive_perRoute(b *testing.B) {
+	routes := testBuildRoutes(150, &testPref{})
+	content := testDefaultPrefix + "cmd149 add user arg1 arg2"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		found := 0
+		for _, route := range routes {
+			cmd, ok := trimPrefix(content, route.getGuildPrefix("guild_id_1"))
+			if !ok {
+				continue
+			}
+			if rr, _ := findRouteRecursive(route, cmdParserDefault(cmd), 1); rr != nil {
+				found++
+			}
+		}
+		if found != 1 {
+			b.Fatal("expected exactly 1 route")
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+const (
+	defaultSuggestMaxDistance    = 2
+	defaultSuggestMaxSuggestions = 3
+)
+
+// SuggestOptions configures "did you mean" suggestions for unknown commands. See Router.Suggest.
+type SuggestOptions struct {
+	// MaxDistance is the largest edit distance between the input and a suggested alias. Defaults to 2.
+	// Inputs shorter than 6 characters are limited to a distance of 1.
+	MaxDistance int
+	// MaxSuggestions limits the number of suggestions, closest first. Defaults to 3.
+	MaxSuggestions int
+	// Respond handles unknown commands with suggestions.
+	//
+	// If nil, unknown root commands are ignored and unknown subcommands enter the Resolve of the parent route.
+	Respond func(ctx context.Context, err *UnknownCommandError)
+}
+
+// UnknownCommandError is produced when a command or subcommand does not exist, but resembles one that does.
+type UnknownCommandError struct {
+	Input       string   // the unknown command or subcommand
+	Alias       []string // aliases of the parent route, empty if the command is a root command
+	Suggestions []string // similar aliases, closest first
+}
+
+func (e *UnknownCommandError) Error() string {
+	suggestions := make([]string, 0, len(e.Suggestions))
+	for _, s := range e.Suggestions {
+		suggestions = append(suggestions, "'"+s+"'")
+	}
+
+	if len(e.Alias) == 0 {
+		return fmt.Sprintf("unknown command '%s', did you mean %s?", e.Input, strings.Join(suggestions, " or "))
+	}
+	return fmt.Sprintf("unknown subcommand '%s' of '%s', did you mean %s?",
+		e.Input, strings.Join(e.Alias, " "), strings.Join(suggestions, " or "))
+}
+
+// Suggest enables "did you mean" suggestions when a command or subcommand is mistyped.
+//
+// A subcommand is considered mistyped when the argument following the deepest matching route
+// resembles an alias of one of its subroutes. It is checked after the filters, permissions and middlewares of the
+// matched route, in place of Handle; the *UnknownCommandError is passed to opts.Respond,
+// or to the Resolve of the matched route if opts.Respond is nil.
+//
+// A root command is considered mistyped when no aliased route matches and the first argument after a non-empty prefix
+// resembles an alias of a root route. It is only passed to opts.Respond, after the middlewares of the Router;
+// routes with no aliases still run, except in exclusive mode where the response replaces the fallback route.
+//
+// Routes declaring positional Args never have mistyped subcommands, since any argument is accepted.
+// Hidden routes, and routes whose filters or permissions reject the message, are never suggested.
+func (r *Router) Suggest(opts SuggestOptions) *Router {
+	if opts.MaxDistance <= 0 {
+		opts.MaxDistance = defaultSuggestMaxDistance
+	}
+	if opts.MaxSuggestions <= 0 {
+		opts.MaxSuggestions = defaultSuggestMaxSuggestions
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.suggest = &opts
+	r.idx = nil
+	return r
+}
+
+// unknownSubcommand returns an error if the argument following the route at depth resembles one of its subroutes.
+// path contains the routes leading to route, inclusive.
+//
+// Routes declaring positional Args accept any argument, so they never have unknown subcommands.
+// Subroutes are only suggested if the message in ctx passes their filters and permissions.
+func unknownSubcommand(ctx context.Context, path []*Route, args []string, depth int, opts *SuggestOptions) *UnknownCommandError {
+	route := path[len(path)-1]
+	if depth == 0 || depth >= len(args) || len(route.args) != 0 {
+		return nil
+	}
+
+	candidates := make([]*Route, 0, len(route.subroutes))
+	for _, sub := range route.subroutes {
+		if permitted(ctx, append(path[:len(path):len(path)], sub)) {
+			candidates = append(candidates, sub)
+		}
+	}
+
+	suggestions := suggest(args[depth], candidates, opts)
+	if len(suggestions) == 0 {
+		return nil
+	}
+	return &UnknownCommandError{Input: args[depth], Alias: args[:depth], Suggestions: suggestions}
+}
+
+// unknownCommand returns an invocation responding to the first argument of a message if it resembles a root route,
+// or nil if it does not. Root routes are only suggested if the message in ctx passes their filters and permissions.
+func (idx *routeIndex) unknownCommand(ctx context.Context) *invocation {
+	msg := utils.GetMsg(ctx)
+
+	for _, g := range idx.groups {
+		prefix, cmd, ok := idx.trimPrefix(ctx, g, loadPrefixes(g.p, msg.GuildID))
+		if !ok || prefix == "" {
+			continue // with an empty prefix, every message would be considered a command
+		}
+		args, err := idx.parse(g, cmd)
+		if err != nil || len(args) == 0 {
+			continue
+		}
+
+		roots := make([]*Route, 0, len(g.roots))
+		for _, root := range g.roots {
+			if permitted(ctx, []*Route{root.route}) {
+				roots = append(roots, root.route)
+			}
+		}
+		if suggestions := suggest(args[0], roots, idx.suggest); len(suggestions) != 0 {
+			unknown := &UnknownCommandError{Input: args[0], Alias: []string{}, Suggestions: suggestions}
+			return &invocation{
+				ctx:     utils.WithErr(utils.WithArgs(utils.WithPrefix(ctx, prefix), args), unknown),
+				args:    args,
+				unknown: unknown,
+				suggest: idx.suggest, wrappers: idx.wrappers,
+			}
+		}
+	}
+
+	return nil
+}
+
+// respond passes the unknown command of the invocation to SuggestOptions.Respond after the middlewares of the Router.
+func (inv *invocation) respond() {
+	respond := func(context.Context) error { return inv.unknown }
+	if err := chainMiddlewares(inv.wrappers, respond)(inv.ctx); err == error(inv.unknown) {
+		inv.suggest.Respond(inv.ctx, inv.unknown)
+	}
+}
+
+// permitted reports if the message in ctx passes the filters and permissions of the last route in path.
+func permitted(ctx context.Context, path []*Route) bool {
+	if _, err := checkFilters(ctx, path); err != nil {
+		return false
+	}
+	if required, ok := requiredPermissions(path); ok {
+		return checkPermissions(ctx, required) == nil
+	}
+	return true
+}
+
+// matchedAlias returns true if any invocation matched an aliased route or failed to parse.
+func matchedAlias(invs []*invocation) bool {
+	for _, inv := range invs {
+		if inv.err != nil || inv.depth > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the visible aliases of routes closest to input.
+func suggest(in string, routes []*Route, opts *SuggestOptions) []string {
+	type candidate struct {
+		alias    string
+		distance int
+	}
+
+	var (
+		input       = strings.ToLower(in)
+		maxDistance = opts.MaxDistance
+		seen        = map[string]bool{}
+		candidates  []candidate
+	)
+	if utf8.RuneCountInString(input) < 6 && maxDistance > 1 {
+		maxDistance = 1
+	}
+
+	for _, r := range routes {
+		if !visibleInHelp(r) {
+			continue
+		}
+		for _, alias := range r.aliases {
+			lower := strings.ToLower(alias)
+			if seen[lower] {
+				continue
+			}
+			seen[lower] = true
+			if d := editDistance(input, lower); d <= maxDistance {
+				candidates = append(candidates, candidate{alias: alias, distance: d})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].alias < candidates[j].alias
+	})
+	if len(candidates) > opts.MaxSuggestions {
+		candidates = candidates[:opts.MaxSuggestions]
+	}
+
+	suggestions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, c.alias)
+	}
+	return suggestions
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// which counts insertions, deletions, substitutions and transpositions of adjacent runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,195 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/pixeltopic/sayori/v2/filter"
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "config", b: "config", expected: 0},
+		{a: "confg", b: "config", expected: 1},
+		{a: "bna", b: "ban", expected: 1},
+		{a: "", b: "abc", expected: 3},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "héllo", b: "hello", expected: 1},
+	}
+
+	for _, c := range testCases {
+		if d := editDistance(c.a, c.b); d != c.expected {
+			t.Errorf("expected distance between %q and %q to be %d, got %d", c.a, c.b, c.expected, d)
+		}
+	}
+}
+
+func TestRouter_Suggest(t *testing.T) {
+	var (
+		ses       = makeMockSes()
+		router    = New(ses)
+		responded *UnknownCommandError
+		resolved  error
+		handled   bool
+	)
+
+	tag := &testCmd{
+		HandleCallback: func(ctx context.Context) error {
+			handled = true
+			return nil
+		},
+		ResolveCallback: func(ctx context.Context) {
+			resolved = utils.GetErr(ctx)
+		},
+	}
+	router.Has(NewRoute(&testPref{}).On("config").Do(&testCmd{}))
+	router.Has(NewRoute(&testPref{}).On("tag").Do(tag).Has(
+		NewSubroute().On("create").Do(&testCmd{}),
+		NewSubroute().On("crate").Do(&testCmd{}).Meta(Meta{Hidden: true}),
+		NewSubroute().On("delete").Do(&testCmd{}),
+	))
+	router.Suggest(SuggestOptions{})
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"tag craete foo"))
+	var unknown *UnknownCommandError
+	if !errors.As(resolved, &unknown) || unknown.Input != "craete" || !strSliceEqual([]string{"create"}, unknown.Suggestions, false) {
+		t.Errorf("expected unknown subcommand to be resolved with a suggestion, got %v", resolved)
+	}
+	if handled {
+		t.Error("expected Handle to be skipped for an unknown subcommand")
+	}
+
+	resolved = nil
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"tag foo"))
+	if resolved != nil || !handled {
+		t.Errorf("expected arguments that do not resemble a subcommand to be handled, got %v", resolved)
+	}
+
+	router.Suggest(SuggestOptions{Respond: func(ctx context.Context, err *UnknownCommandError) {
+		responded = err
+	}})
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"confg"))
+	if responded == nil || responded.Input != "confg" || !strSliceEqual([]string{"config"}, responded.Suggestions, false) {
+		t.Errorf("expected unknown root command to be responded to with a suggestion, got %v", responded)
+	}
+	if expected := "unknown command 'confg', did you mean 'config'?"; responded != nil && responded.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, responded.Error())
+	}
+
+	responded = nil
+	router.onMessageCreate(ses, makeMockMsg("confg"))
+	if responded != nil {
+		t.Errorf("expected messages without a prefix to be ignored, got %v", responded)
+	}
+}
+
+func TestRouter_SuggestMiddlewares(t *testing.T) {
+	var (
+		ses       = makeMockSes()
+		router    = New(ses)
+		trace     []string
+		responded *UnknownCommandError
+		resolved  error
+		blacklist = &testMiddleware{name: "blacklist", trace: &trace}
+	)
+
+	router.Use(blacklist)
+	router.Has(NewRoute(&testPref{}).On("tag").Do(&testCmd{
+		ResolveCallback: func(ctx context.Context) {
+			resolved = utils.GetErr(ctx)
+		},
+	}).Has(NewSubroute().On("create").Do(&testCmd{})))
+	router.Suggest(SuggestOptions{Respond: func(ctx context.Context, err *UnknownCommandError) {
+		responded = err
+	}})
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"tag craete"))
+	if responded == nil || !strSliceEqual([]string{"blacklist"}, trace, false) {
+		t.Errorf("expected unknown subcommand to be responded to after middlewares %v ran, got %v", trace, responded)
+	}
+
+	responded = nil
+	blacklist.err = errors.New("denied")
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"tag craete"))
+	if responded != nil {
+		t.Errorf("expected no suggestion for a message rejected by a middleware, got %v", responded)
+	}
+	if resolved != blacklist.err {
+		t.Errorf("expected middleware error to be resolved, got %v", resolved)
+	}
+}
+
+func TestRouter_SuggestPermitted(t *testing.T) {
+	var (
+		ses       = makeMockSes()
+		router    = New(ses)
+		trace     []string
+		responded *UnknownCommandError
+		handled   []string
+		blacklist = &testMiddleware{name: "blacklist", trace: &trace}
+	)
+
+	handle := func(name string) *testCmd {
+		return &testCmd{HandleCallback: func(ctx context.Context) error {
+			handled = append(handled, name)
+			return nil
+		}}
+	}
+
+	router.Use(blacklist)
+	router.Has(NewRoute(&testPref{}).On("config").Do(handle("config")).Filter(filter.MsgFromBot, FilterDrop))
+	router.Has(NewRoute(&testPref{}).On("role").Do(handle("role")).Has(
+		NewSubroute().On("add").Args(Arg{Name: "role"}).Do(handle("role add")).Has(
+			NewSubroute().On("admins").Do(handle("role add admins")),
+		),
+		NewSubroute().On("ban").Do(handle("role ban")).Filter(filter.MsgFromBot, FilterDrop),
+	))
+	router.Suggest(SuggestOptions{Respond: func(ctx context.Context, err *UnknownCommandError) {
+		responded = err
+	}})
+
+	bot := func(content string) *discordgo.MessageCreate {
+		m := makeMockMsg(content)
+		m.Author.Bot = true
+		return m
+	}
+
+	testCases := []struct {
+		name      string
+		m         *discordgo.MessageCreate
+		denied    bool
+		responded bool
+		handled   []string
+	}{
+		{name: "positional args", m: makeMockMsg(testDefaultPrefix + "role add admin"), handled: []string{"role add"}},
+		{name: "subroute", m: makeMockMsg(testDefaultPrefix + "role bna"), responded: true},
+		{name: "filtered subroute", m: bot(testDefaultPrefix + "role bna"), handled: []string{"role"}},
+		{name: "root", m: makeMockMsg(testDefaultPrefix + "confg"), responded: true},
+		{name: "filtered root", m: bot(testDefaultPrefix + "confg")},
+		{name: "blacklisted root", m: makeMockMsg(testDefaultPrefix + "confg"), denied: true},
+	}
+
+	for _, c := range testCases {
+		responded, handled = nil, nil
+		blacklist.err = nil
+		if c.denied {
+			blacklist.err = errors.New("denied")
+		}
+
+		router.onMessageCreate(ses, c.m)
+		if (responded != nil) != c.responded {
+			t.Errorf("%s: expected a suggestion to be responded: %v, got %v", c.name, c.responded, responded)
+		}
+		if !strSliceEqual(c.handled, handled, false) {
+			t.Errorf("%s: expected handlers %v to run, got %v", c.name, c.handled, handled)
+		}
+	}
+}
@@ -8,6 +8,29 @@ import (
 
 type ctxKey int
 
+// Flags maps the name of a flag to the values it was passed with. A flag that takes no value maps to an empty slice.
+type Flags map[string][]string
+
+// Has returns true if the flag was passed.
+func (f Flags) Has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// Value returns the last value of the flag, or an empty string if it has none.
+func (f Flags) Value(name string) string {
+	v := f[name]
+	if len(v) == 0 {
+		return ""
+	}
+	return v[len(v)-1]
+}
+
+// Values returns all values of the flag in the order they were passed.
+func (f Flags) Values(name string) []string {
+	return f[name]
+}
+
 const (
 	ctxSesKey ctxKey = iota
 	ctxMsgKey
@@ -15,8 +38,23 @@ const (
 	ctxAliasKey
 	ctxArgsKey
 	ctxCmdErrKey
+	ctxArgValuesKey
+	ctxFlagsKey
+	ctxEditKey
+	ctxReactionKey
 )
 
+// reaction is a Discord MessageReaction that was added or removed.
+type reaction struct {
+	r       *discordgo.MessageReaction
+	removed bool
+}
+
+// edit records that a Message was edited, along with the Message before the edit if it is known.
+type edit struct {
+	before *discordgo.Message
+}
+
 // WithSes attaches a Discord Session to Context.
 func WithSes(ctx context.Context, ses *discordgo.Session) context.Context {
 	return context.WithValue(ctx, ctxSesKey, ses)
@@ -87,6 +125,34 @@ func GetArgs(ctx context.Context) []string {
 	return v
 }
 
+// WithFlags attaches Command Flags to Context.
+func WithFlags(ctx context.Context, flags Flags) context.Context {
+	return context.WithValue(ctx, ctxFlagsKey, flags)
+}
+
+// GetFlags returns Command Flags from Context.
+func GetFlags(ctx context.Context) Flags {
+	v, ok := ctx.Value(ctxFlagsKey).(Flags)
+	if !ok {
+		return Flags{}
+	}
+	return v
+}
+
+// WithArgValues attaches converted Command Args, keyed by argument name, to Context.
+func WithArgValues(ctx context.Context, values map[string]interface{}) context.Context {
+	return context.WithValue(ctx, ctxArgValuesKey, values)
+}
+
+// GetArgValues returns converted Command Args from Context.
+func GetArgValues(ctx context.Context) map[string]interface{} {
+	v, ok := ctx.Value(ctxArgValuesKey).(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return v
+}
+
 // WithErr attaches a Command Err to Context.
 func WithErr(ctx context.Context, err error) context.Context {
 	return context.WithValue(ctx, ctxCmdErrKey, err)
@@ -100,3 +166,44 @@ func GetErr(ctx context.Context) error {
 	}
 	return v
 }
+
+// WithEdit marks the Message in Context as edited. before is the Message before the edit, and may be nil if unknown.
+func WithEdit(ctx context.Context, before *discordgo.Message) context.Context {
+	return context.WithValue(ctx, ctxEditKey, edit{before: before})
+}
+
+// IsEdit returns true if the Message in Context was edited, rather than newly sent.
+func IsEdit(ctx context.Context) bool {
+	_, ok := ctx.Value(ctxEditKey).(edit)
+	return ok
+}
+
+// GetBeforeEdit returns the Message in Context as it was before it was edited.
+// If the Message was not edited or its previous version is unknown, returns nil.
+func GetBeforeEdit(ctx context.Context) *discordgo.Message {
+	v, ok := ctx.Value(ctxEditKey).(edit)
+	if !ok {
+		return nil
+	}
+	return v.before
+}
+
+// WithReaction attaches a Discord MessageReaction to Context. removed is true if the reaction was removed rather than added.
+func WithReaction(ctx context.Context, r *discordgo.MessageReaction, removed bool) context.Context {
+	return context.WithValue(ctx, ctxReactionKey, reaction{r: r, removed: removed})
+}
+
+// GetReaction returns a Discord MessageReaction from Context. If MessageReaction not present, returns nil.
+func GetReaction(ctx context.Context) *discordgo.MessageReaction {
+	v, ok := ctx.Value(ctxReactionKey).(reaction)
+	if !ok {
+		return nil
+	}
+	return v.r
+}
+
+// IsReactionRemoved returns true if the MessageReaction in Context was removed rather than added.
+func IsReactionRemoved(ctx context.Context) bool {
+	v, ok := ctx.Value(ctxReactionKey).(reaction)
+	return ok && v.removed
+}
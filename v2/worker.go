@@ -0,0 +1,257 @@
+package v2
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// OverflowPolicy determines what happens to a message when the queue of a worker pool is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop silently ignores the message.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock waits for room in the queue, blocking the DiscordGo event handler.
+	OverflowBlock
+	// OverflowBusy ignores the message and replies with WorkerOptions.Busy.
+	OverflowBusy
+)
+
+// DefaultBusy is the reply of OverflowBusy if WorkerOptions.Busy is empty.
+const DefaultBusy = "I'm busy right now, please try again in a moment."
+
+// WorkerOptions configures the worker pool of a Router.
+type WorkerOptions struct {
+	// Workers is the number of goroutines handling messages. It defaults to 1.
+	Workers int
+	// Queue is the number of messages waiting for a worker before Overflow applies.
+	// If Key is set, each worker has its own queue of this size.
+	Queue int
+	// Overflow is the policy of messages arriving when the queue is full.
+	Overflow OverflowPolicy
+	// Busy is the reply of OverflowBusy. It defaults to DefaultBusy.
+	Busy string
+	// Key returns the key of an event, such as ByChannel or ByUser.
+	// Events with the same key are handled one at a time, in the order they were received. If nil, events are unordered.
+	//
+	// Each key is hashed onto the queue of one worker, which may be shared by other keys. A slow handler therefore
+	// also delays events of unrelated keys on the same worker until it returns; more Workers make this less likely.
+	Key func(ctx context.Context) string
+}
+
+// ByChannel is a WorkerOptions.Key serializing events per channel.
+func ByChannel(ctx context.Context) string {
+	if msg := utils.GetMsg(ctx); msg != nil {
+		return msg.ChannelID
+	}
+	if reaction := utils.GetReaction(ctx); reaction != nil {
+		return reaction.ChannelID
+	}
+	return ""
+}
+
+// ByUser is a WorkerOptions.Key serializing events per user.
+func ByUser(ctx context.Context) string {
+	if msg := utils.GetMsg(ctx); msg != nil && msg.Author != nil {
+		return msg.Author.ID
+	}
+	if reaction := utils.GetReaction(ctx); reaction != nil {
+		return reaction.UserID
+	}
+	return ""
+}
+
+// workerPool runs jobs on a fixed number of goroutines.
+//
+// No lock is held while a job is sent to a queue, so a blocked submit never holds up stop or the Router.
+type workerPool struct {
+	opts   WorkerOptions
+	queues []chan func() // a single queue shared by all workers, or one queue per worker if keyed
+	done   chan struct{} // closed when the pool is stopped, unblocking submits waiting for room
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+	sending int // submits in progress; the queues are closed once the pool is stopped and none are left
+}
+
+// newWorkerPool returns a started workerPool.
+func newWorkerPool(opts WorkerOptions) *workerPool {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.Queue < 0 {
+		opts.Queue = 0
+	}
+	if opts.Busy == "" {
+		opts.Busy = DefaultBusy
+	}
+
+	p := &workerPool{opts: opts, done: make(chan struct{})}
+	if opts.Key == nil {
+		q := make(chan func(), opts.Queue)
+		for i := 0; i < opts.Workers; i++ {
+			p.start(q)
+		}
+		p.queues = []chan func(){q}
+		return p
+	}
+	for i := 0; i < opts.Workers; i++ {
+		q := make(chan func(), opts.Queue)
+		p.start(q)
+		p.queues = append(p.queues, q)
+	}
+	return p
+}
+
+// start runs a worker consuming q until it is closed.
+func (p *workerPool) start(q chan func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for job := range q {
+			job()
+		}
+	}()
+}
+
+// submit queues job, which handles the event in ctx, according to the overflow policy of the pool.
+// Returns false if the job was not queued.
+func (p *workerPool) submit(ctx context.Context, job func()) bool {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return false
+	}
+	p.sending++
+	p.mu.Unlock()
+
+	queued := p.send(ctx, job)
+	p.release()
+
+	if !queued && p.opts.Overflow == OverflowBusy {
+		if ses, msg := utils.GetSes(ctx), utils.GetMsg(ctx); ses != nil && msg != nil {
+			_, _ = ses.ChannelMessageSend(msg.ChannelID, p.opts.Busy)
+		}
+	}
+	return queued
+}
+
+// send sends job to the queue of the event in ctx. Returns false if the queue is full, or the pool is stopped
+// while waiting for room under OverflowBlock.
+func (p *workerPool) send(ctx context.Context, job func()) bool {
+	q := p.queues[0]
+	if len(p.queues) > 1 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(p.opts.Key(ctx)))
+		q = p.queues[h.Sum32()%uint32(len(p.queues))]
+	}
+
+	if p.opts.Overflow == OverflowBlock {
+		select {
+		case q <- job:
+			return true
+		case <-p.done:
+			return false
+		}
+	}
+	select {
+	case q <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// release ends a submit, closing the queues if it was the last one of a stopped pool.
+func (p *workerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sending--
+	if p.stopped && p.sending == 0 {
+		p.closeQueues()
+	}
+}
+
+// stop stops the pool without waiting for it. Queued jobs still run, and jobs submitted afterwards are ignored.
+func (p *workerPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	close(p.done)
+	if p.sending == 0 {
+		p.closeQueues()
+	}
+}
+
+// closeQueues closes the queues, so workers exit once they are empty.
+//
+// p.mu must be held, and no submit may be sending.
+func (p *workerPool) closeQueues() {
+	for _, q := range p.queues {
+		close(q)
+	}
+}
+
+// Workers runs handlers on a pool of goroutines with a bounded queue, instead of the goroutine of the DiscordGo event,
+// limiting how many messages are handled at once. Matching routes, suggestions and Await are resolved before
+// messages are queued, so only messages invoking a route take room in the queue.
+//
+// With WorkerOptions.Key, messages sharing a key are handled in order. DiscordGo only receives events in order
+// if Session.SyncEvents is set; otherwise each event already runs on its own goroutine and may be reordered.
+//
+// Handlers waiting in Await, Confirm.Ask or Paginator.Run occupy their worker until they return.
+//
+// If Workers is called multiple times, the previous pool is stopped after its queued messages are handled.
+func (r *Router) Workers(opts WorkerOptions) *Router {
+	r.mu.Lock()
+	old := r.pool
+	r.poolOpts = &opts
+	r.pool = nil
+	if !r.closing {
+		r.pool = newWorkerPool(opts)
+	}
+	r.idx = nil
+	r.mu.Unlock()
+
+	if old != nil {
+		old.stop()
+	}
+	return r
+}
+
+// blocksEvents reports if handlers block s from receiving events until they return. This is the case if s dispatches
+// events synchronously, unless handlers run on a worker pool that does not wait for room in its queue.
+func (idx *routeIndex) blocksEvents(s *discordgo.Session) bool {
+	return s != nil && s.SyncEvents && (idx.pool == nil || idx.pool.opts.Overflow == OverflowBlock)
+}
+
+// concurrent reports if the routes handling one event run on their own goroutines, as DiscordGo handlers do.
+// This is the case if s dispatches events asynchronously and handlers do not run on a worker pool.
+func (idx *routeIndex) concurrent(s *discordgo.Session) bool {
+	return idx.pool == nil && (s == nil || !s.SyncEvents)
+}
+
+// execute runs job, which handles the event in ctx, on the worker pool of the index if it has one.
+// done is called once job returns, or if it is not queued.
+func (idx *routeIndex) execute(ctx context.Context, done func(), job func()) {
+	run := func() {
+		defer done()
+		job()
+	}
+	if idx.pool == nil {
+		run()
+		return
+	}
+	if !idx.pool.submit(ctx, run) {
+		done()
+	}
+}
@@ -0,0 +1,146 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pixeltopic/sayori/v2/utils"
+)
+
+func TestWorkerPool_overflow(t *testing.T) {
+	var (
+		p       = newWorkerPool(WorkerOptions{Workers: 1, Queue: 1, Overflow: OverflowDrop})
+		started = make(chan struct{})
+		release = make(chan struct{})
+		ctx     = context.Background()
+	)
+	defer p.stop()
+	defer close(release)
+
+	if !p.submit(ctx, func() { close(started); <-release }) {
+		t.Fatal("expected job to be queued")
+	}
+	<-started
+
+	if !p.submit(ctx, func() {}) {
+		t.Error("expected job to be queued while the worker is busy")
+	}
+	if p.submit(ctx, func() {}) {
+		t.Error("expected job to be dropped when the queue is full")
+	}
+}
+
+func TestWorkerPool_key(t *testing.T) {
+	var (
+		p   = newWorkerPool(WorkerOptions{Workers: 4, Queue: 100, Overflow: OverflowBlock, Key: ByChannel})
+		mu  sync.Mutex
+		got = map[string][]int{}
+	)
+
+	for i := 0; i < 90; i++ {
+		var (
+			i       = i
+			channel = fmt.Sprintf("channel_id_%d", i%3)
+			msg     = makeMockMsg("").Message
+		)
+		msg.ChannelID = channel
+		p.submit(utils.WithMsg(context.Background(), msg), func() {
+			time.Sleep(time.Duration(i%4) * time.Millisecond)
+			mu.Lock()
+			got[channel] = append(got[channel], i)
+			mu.Unlock()
+		})
+	}
+	p.stop()
+	p.wg.Wait()
+
+	for channel, order := range got {
+		if len(order) != 30 {
+			t.Errorf("%s: expected 30 jobs, got %d", channel, len(order))
+		}
+		for j := 1; j < len(order); j++ {
+			if order[j] < order[j-1] {
+				t.Errorf("%s: expected jobs to run in order, got %v", channel, order)
+				break
+			}
+		}
+	}
+}
+
+func TestRouter_Workers(t *testing.T) {
+	var (
+		ses    = makeMockSes()
+		router = New(ses).Workers(WorkerOptions{Workers: 2, Queue: 10})
+		fired  = make(chan string, 1)
+	)
+	defer router.Close()
+
+	router.Has(NewRoute(&testPref{}).On("ping").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		fired <- utils.GetMsg(ctx).Content
+		return nil
+	}}))
+
+	router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"ping"))
+	select {
+	case content := <-fired:
+		if content != testDefaultPrefix+"ping" {
+			t.Errorf("expected handler to receive the message, got %q", content)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected handler to run on the worker pool")
+	}
+}
+
+func TestRouter_Workers_close(t *testing.T) {
+	var (
+		ses     = makeMockSes()
+		router  = New(ses).Workers(WorkerOptions{Workers: 1, Queue: 1, Overflow: OverflowBlock})
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+
+	router.Has(NewRoute(&testPref{}).On("await").Do(&testCmd{HandleCallback: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := AwaitReply(ctx, nil)
+		return err
+	}}))
+	router.HasOnce(NewRoute(&testPref{}).On("once").Do(testHandler{}))
+
+	// the worker is busy, the queue is full and further messages block on submit
+	go router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+"await"))
+	<-started
+	for _, content := range []string{"once", "await", "once"} {
+		wg.Add(1)
+		go func(content string) {
+			defer wg.Done()
+			router.onMessageCreate(ses, makeMockMsg(testDefaultPrefix+content))
+		}(content)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		_ = router.Close()
+		close(closed)
+	}()
+	close(release)
+
+	done := make(chan struct{})
+	go func() {
+		<-closed
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Close not to deadlock with queued handlers")
+	}
+}